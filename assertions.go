@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	equalsAssertionType   = "equals"
+	containsAssertionType = "contains"
+	// Header only: an element of a comma separated header value, case-insensitively
+	listContainsAssertionType = "list_contains"
+	regexAssertionType        = "regex"
+	jsonPathAssertionType     = "jsonpath"
+	existsAssertionType       = "exists"
+)
+
+type assertionConfig struct {
+	Name  string `yaml:"name"`
+	Type  string `yaml:"type"`
+	Value string `yaml:"value"`
+}
+
+type responseBodyAssertion struct {
+	assertionType string
+	value         string
+	regex         *regexp.Regexp
+	jsonPath      *jsonPathExpression
+}
+
+type responseHeaderAssertion struct {
+	name          string
+	assertionType string
+	value         string
+	regex         *regexp.Regexp
+}
+
+func newResponseBodyAssertion(config *assertionConfig) (*responseBodyAssertion, error) {
+	assertion := &responseBodyAssertion{
+		assertionType: config.Type,
+		value:         config.Value,
+	}
+
+	var err error
+
+	switch config.Type {
+	case equalsAssertionType, containsAssertionType:
+	case regexAssertionType:
+		if assertion.regex, err = regexp.Compile(config.Value); err != nil {
+			return nil, fmt.Errorf("error compiling regex %s: %v", config.Value, err)
+		}
+	case jsonPathAssertionType:
+		if assertion.jsonPath, err = newJSONPathExpression(config.Value); err != nil {
+			return nil, fmt.Errorf("error parsing JSONPath expression %s: %v", config.Value, err)
+		}
+	default:
+		return nil, fmt.Errorf("response body assertion type must be %s, %s, %s or %s", equalsAssertionType, containsAssertionType, regexAssertionType, jsonPathAssertionType)
+	}
+
+	return assertion, nil
+}
+
+// Returns whether the response body matches the assertion, and an error if the response body could not be evaluated
+func (rba *responseBodyAssertion) assert(responseBodyBytes []byte) (bool, error) {
+	switch rba.assertionType {
+	case containsAssertionType:
+		return bytes.Contains(responseBodyBytes, []byte(rba.value)), nil
+	case regexAssertionType:
+		return rba.regex.Match(responseBodyBytes), nil
+	case jsonPathAssertionType:
+		return rba.jsonPath.evaluate(responseBodyBytes)
+	default:
+		return string(responseBodyBytes) == rba.value, nil
+	}
+}
+
+func (rba *responseBodyAssertion) getStatusLabelValue() string {
+	switch rba.assertionType {
+	case containsAssertionType:
+		return noMatchResponseBodyContainsStatusMetricStatusLabelValue
+	case regexAssertionType:
+		return noMatchResponseBodyRegexStatusMetricStatusLabelValue
+	case jsonPathAssertionType:
+		return noMatchResponseBodyJSONPathStatusMetricStatusLabelValue
+	default:
+		return noMatchResponseBodyStatusMetricStatusLabelValue
+	}
+}
+
+func newResponseHeaderAssertion(config *assertionConfig) (*responseHeaderAssertion, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("response header assertion name must not be empty")
+	}
+
+	assertion := &responseHeaderAssertion{
+		name:          http.CanonicalHeaderKey(config.Name),
+		assertionType: config.Type,
+		value:         config.Value,
+	}
+
+	var err error
+
+	switch config.Type {
+	case existsAssertionType, equalsAssertionType, containsAssertionType, listContainsAssertionType:
+	case regexAssertionType:
+		if assertion.regex, err = regexp.Compile(config.Value); err != nil {
+			return nil, fmt.Errorf("error compiling regex %s: %v", config.Value, err)
+		}
+	default:
+		return nil, fmt.Errorf("response header assertion type must be %s, %s, %s, %s or %s", existsAssertionType, equalsAssertionType, containsAssertionType, listContainsAssertionType, regexAssertionType)
+	}
+
+	return assertion, nil
+}
+
+// Returns whether any of the response header's values matches the assertion
+func (rha *responseHeaderAssertion) assert(responseHeader http.Header) bool {
+	values := responseHeader.Values(rha.name)
+
+	if rha.assertionType == existsAssertionType {
+		return len(values) > 0
+	}
+
+	for _, value := range values {
+		if rha.assertionType == regexAssertionType && rha.regex.MatchString(value) {
+			return true
+		}
+
+		if rha.assertionType == equalsAssertionType && value == rha.value {
+			return true
+		}
+
+		if rha.assertionType == containsAssertionType && strings.Contains(value, rha.value) {
+			return true
+		}
+
+		if rha.assertionType == listContainsAssertionType && listHeaderValueContains(value, rha.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns whether a comma separated header value (for example: Allow or Access-Control-Allow-Methods) contains the element, case-insensitively
+func listHeaderValueContains(headerValue string, element string) bool {
+	for _, headerElement := range strings.Split(headerValue, ",") {
+		if strings.EqualFold(strings.TrimSpace(headerElement), element) {
+			return true
+		}
+	}
+
+	return false
+}
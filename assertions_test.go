@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseBodyAssertion_Assert(t *testing.T) {
+	responseBody := []byte(`{"status": "ok", "time": "2022-03-01T10:00:00Z"}`)
+	assertions := []struct {
+		config           assertionConfig
+		matched          bool
+		statusLabelValue string
+	}{
+		{assertionConfig{Type: equalsAssertionType, Value: `{"status": "ok"}`}, false, noMatchResponseBodyStatusMetricStatusLabelValue},
+		{assertionConfig{Type: containsAssertionType, Value: `"status": "ok"`}, true, noMatchResponseBodyContainsStatusMetricStatusLabelValue},
+		{assertionConfig{Type: containsAssertionType, Value: "error"}, false, noMatchResponseBodyContainsStatusMetricStatusLabelValue},
+		{assertionConfig{Type: regexAssertionType, Value: `"time": "\d{4}-\d{2}-\d{2}T`}, true, noMatchResponseBodyRegexStatusMetricStatusLabelValue},
+		{assertionConfig{Type: regexAssertionType, Value: `^ok$`}, false, noMatchResponseBodyRegexStatusMetricStatusLabelValue},
+		{assertionConfig{Type: jsonPathAssertionType, Value: `$.status == "ok"`}, true, noMatchResponseBodyJSONPathStatusMetricStatusLabelValue},
+		{assertionConfig{Type: jsonPathAssertionType, Value: `$.status == "down"`}, false, noMatchResponseBodyJSONPathStatusMetricStatusLabelValue},
+	}
+
+	for _, testAssertion := range assertions {
+		responseBodyAssertion, err := newResponseBodyAssertion(&testAssertion.config)
+		require.NoError(t, err)
+
+		matched, err := responseBodyAssertion.assert(responseBody)
+		require.NoError(t, err)
+
+		assert.Equal(t, testAssertion.matched, matched, testAssertion.config.Value)
+		assert.Equal(t, testAssertion.statusLabelValue, responseBodyAssertion.getStatusLabelValue())
+	}
+}
+
+func TestNewResponseBodyAssertion_BadConfig(t *testing.T) {
+	for _, config := range []assertionConfig{
+		{Type: "starts_with", Value: "ok"},
+		{Type: regexAssertionType, Value: "(ok"},
+		{Type: jsonPathAssertionType, Value: "status == ok"},
+		{Type: jsonPathAssertionType, Value: `$.status = "ok"`},
+	} {
+		_, err := newResponseBodyAssertion(&config)
+		assert.Error(t, err, config.Value)
+	}
+}
+
+func TestRun_NoMatchResponseBodyJSONPathStatus(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(apiUrlEnvName, "https://example.api:1234")
+	require.NoError(t, err)
+
+	err = os.Setenv(methodEnvName, http.MethodGet)
+	require.NoError(t, err)
+
+	err = os.Setenv(apiResponseTimeoutEnvName, "10")
+	require.NoError(t, err)
+
+	err = os.Setenv(expectedStatusCodeEnvName, "200")
+	require.NoError(t, err)
+
+	err = os.Setenv(expectedBodyEnvName, `$.items.length() > 0`)
+	require.NoError(t, err)
+
+	err = os.Setenv(expectedBodyMatchEnvName, jsonPathAssertionType)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234",
+		httpmock.NewStringResponder(http.StatusOK, `{"items": []}`))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			require.NotNil(t, metrics)
+
+			assert.Len(t, metrics, 3)
+
+			for _, metric := range metrics {
+				if metric["__name__"] == statusMetricName {
+					assert.Len(t, metric, 12)
+					assert.Equal(t, noMatchResponseBodyJSONPathStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
+					assert.Equal(t, `{"items": []}`, metric[statusMetricResponseBodyLabelName])
+					assert.Equal(t, `$.items.length() > 0`, metric[statusMetricExpectedResponseBodyLabelName])
+				}
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	os.Clearenv()
+}
+
+func TestResponseHeaderAssertion_Assert(t *testing.T) {
+	responseHeader := http.Header{}
+	responseHeader.Add("Content-Type", "application/json; charset=utf-8")
+	responseHeader.Add("Cache-Control", "no-cache")
+	responseHeader.Add("Cache-Control", "no-store")
+	responseHeader.Add("X-Version", "1.4.2")
+	responseHeader.Add("Allow", "GET, HEAD")
+	responseHeader.Add("Allow", "OPTIONS")
+
+	assertions := []struct {
+		config  assertionConfig
+		matched bool
+	}{
+		{assertionConfig{Name: "x-version", Type: existsAssertionType}, true},
+		{assertionConfig{Name: "X-Request-Id", Type: existsAssertionType}, false},
+		{assertionConfig{Name: "Cache-Control", Type: equalsAssertionType, Value: "no-store"}, true},
+		{assertionConfig{Name: "Cache-Control", Type: equalsAssertionType, Value: "public"}, false},
+		{assertionConfig{Name: "Content-Type", Type: regexAssertionType, Value: `^application/json`}, true},
+		{assertionConfig{Name: "X-Version", Type: regexAssertionType, Value: `^2\.`}, false},
+		{assertionConfig{Name: "Cache-Control", Type: containsAssertionType, Value: "store"}, true},
+		{assertionConfig{Name: "Allow", Type: containsAssertionType, Value: "GE"}, true},
+		{assertionConfig{Name: "Allow", Type: containsAssertionType, Value: "options"}, false},
+		{assertionConfig{Name: "Allow", Type: listContainsAssertionType, Value: "HEAD"}, true},
+		{assertionConfig{Name: "Allow", Type: listContainsAssertionType, Value: "options"}, true},
+		{assertionConfig{Name: "Allow", Type: listContainsAssertionType, Value: "POST"}, false},
+		{assertionConfig{Name: "Allow", Type: listContainsAssertionType, Value: "GE"}, false},
+	}
+
+	for _, testAssertion := range assertions {
+		responseHeaderAssertion, err := newResponseHeaderAssertion(&testAssertion.config)
+		require.NoError(t, err)
+
+		assert.Equal(t, testAssertion.matched, responseHeaderAssertion.assert(responseHeader), testAssertion.config)
+	}
+}
+
+func TestNewResponseHeaderAssertion_BadConfig(t *testing.T) {
+	for _, config := range []assertionConfig{
+		{Type: existsAssertionType},
+		{Name: "X-Version", Type: jsonPathAssertionType, Value: "$"},
+		{Name: "X-Version", Type: regexAssertionType, Value: "(1"},
+	} {
+		_, err := newResponseHeaderAssertion(&config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestRun_NoMatchResponseHeaderStatus(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(checksEnvName, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+    header_assertions:
+      - name: Content-Type
+        type: regex
+        value: ^application/json
+    expected_body: success
+`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		func(request *http.Request) (*http.Response, error) {
+			response := httpmock.NewStringResponse(http.StatusOK, "success")
+			response.Header.Set("Content-Type", "text/plain")
+
+			return response, nil
+		})
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			require.NotNil(t, metrics)
+
+			assert.Len(t, metrics, 3)
+
+			for _, metric := range metrics {
+				if metric["__name__"] == statusMetricName {
+					assert.Equal(t, noMatchResponseHeaderStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
+					assert.Equal(t, "Content-Type", metric[statusMetricResponseHeaderLabelName])
+					assert.Equal(t, regexAssertionType, metric[statusMetricResponseHeaderAssertionLabelName])
+					assert.Equal(t, "text/plain", metric[statusMetricResponseHeaderValueLabelName])
+					assert.Equal(t, "^application/json", metric[statusMetricExpectedResponseHeaderLabelName])
+				}
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	os.Clearenv()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	leafCertificateType         = "leaf"
+	intermediateCertificateType = "intermediate"
+	rootCertificateType         = "root"
+	hoursInDay                  = 24
+)
+
+func getCertificateType(certificates []*x509.Certificate, index int) string {
+	if index == 0 {
+		return leafCertificateType
+	}
+
+	certificate := certificates[index]
+	if index == len(certificates)-1 && certificate.Subject.String() == certificate.Issuer.String() {
+		return rootCertificateType
+	}
+
+	return intermediateCertificateType
+}
+
+func getCertificateExpiryDays(certificate *x509.Certificate) float64 {
+	return time.Until(certificate.NotAfter).Hours() / hoursInDay
+}
+
+// Returns the host the peer certificate should be valid for: the SNI server name, or the URL host if no SNI was sent (IP address)
+func getCertificateHost(tlsState *tls.ConnectionState, urlHost string) string {
+	if tlsState.ServerName != "" {
+		return tlsState.ServerName
+	}
+
+	return urlHost
+}
+
+func (ac *apiCheck) getCertificateGaugeObservers(tlsState *tls.ConnectionState, urlHost string) []metricRegister {
+	gaugeObservers := make([]metricRegister, 0)
+
+	if len(tlsState.PeerCertificates) == 0 {
+		debugLogger.Println("No peer certificates")
+		return gaugeObservers
+	}
+
+	for index := range tlsState.PeerCertificates {
+		gaugeObservers = append(gaugeObservers, ac.getCertificateExpiryGaugeObserver(tlsState.PeerCertificates, index))
+	}
+
+	return append(gaugeObservers, ac.getCertificateHostMatchGaugeObserver(tlsState.PeerCertificates[0], getCertificateHost(tlsState, urlHost)))
+}
+
+func (ac *apiCheck) getCertificateExpiryGaugeObserver(certificates []*x509.Certificate, index int) *float64GaugeObserver {
+	certificate := certificates[index]
+	expiryDays := getCertificateExpiryDays(certificate)
+	certificateType := getCertificateType(certificates, index)
+
+	return newFloat64GaugeObserver(certificateExpiryMetricName, expiryDays, []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.Int(certificateIndexLabelName, index),
+		attribute.String(certificateTypeLabelName, certificateType),
+		attribute.String(certificateSubjectLabelName, certificate.Subject.String()),
+		attribute.String(certificateIssuerLabelName, certificate.Issuer.String()),
+		attribute.String(unitLabelName, certificateExpiryMetricUnitLabelValue),
+	}, "API certificate days until expiry")
+}
+
+func (ac *apiCheck) getCertificateHostMatchGaugeObserver(leafCertificate *x509.Certificate, host string) *int64GaugeObserver {
+	hostMatch := 1
+	if err := leafCertificate.VerifyHostname(host); err != nil {
+		debugLogger.Printf("Certificate does not match host %s: %v\n", host, err)
+		hostMatch = 0
+	}
+
+	return newInt64GaugeObserver(certificateHostMatchMetricName, int64(hostMatch), []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(certificateHostLabelName, host),
+		attribute.String(certificateSubjectLabelName, leafCertificate.Subject.String()),
+		attribute.String(certificateIssuerLabelName, leafCertificate.Issuer.String()),
+	}, "API certificate matches host")
+}
+
+// Returns a certificate expiring status if a certificate of the chain expires within the threshold
+func (ac *apiCheck) getCertificateExpiringStatusGaugeObserver(responseStatusCode int, tlsState *tls.ConnectionState) *int64GaugeObserver {
+	if ac.certificateExpiryThresholdDays == 0 || tlsState == nil {
+		return nil
+	}
+
+	for index, certificate := range tlsState.PeerCertificates {
+		expiryDays := getCertificateExpiryDays(certificate)
+		if expiryDays > float64(ac.certificateExpiryThresholdDays) {
+			continue
+		}
+
+		return newInt64GaugeObserver(statusMetricName, statusMetricValue, []attribute.KeyValue{
+			attribute.String(checkNameLabelName, ac.name),
+			attribute.String(urlLabelName, ac.getRedactedURL()),
+			attribute.String(methodLabelName, ac.method),
+			attribute.String(statusMetricStatusLabelName, certificateExpiringStatusMetricStatusLabelValue),
+			attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
+			attribute.String(certificateTypeLabelName, getCertificateType(tlsState.PeerCertificates, index)),
+			attribute.String(certificateSubjectLabelName, certificate.Subject.String()),
+			attribute.Int(statusMetricCertificateExpiryDaysLabelName, int(math.Floor(expiryDays))),
+			attribute.Int(statusMetricCertificateExpiryThresholdDaysLabelName, ac.certificateExpiryThresholdDays),
+		}, statusObserverDescription)
+	}
+
+	debugLogger.Println("No certificate expiring status")
+	return nil
+}
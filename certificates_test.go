@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Creates a certificate for the DNS name, signed by the parent (or self-signed if parent is nil)
+func createTestCertificate(t *testing.T, commonName string, dnsName string, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	if dnsName != "" {
+		template.DNSNames = []string{dnsName}
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		parent, parentKey = template, key
+	}
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	require.NoError(t, err)
+
+	return certificate, key
+}
+
+func TestGetCertificateGaugeObservers(t *testing.T) {
+	intermediate, intermediateKey := createTestCertificate(t, "Test CA", "", time.Now().Add(365*24*time.Hour), nil, nil)
+	leaf, _ := createTestCertificate(t, "example.api", "example.api", time.Now().Add(30*24*time.Hour+time.Hour), intermediate, intermediateKey)
+	tlsState := &tls.ConnectionState{
+		ServerName:       "example.api",
+		PeerCertificates: []*x509.Certificate{leaf, intermediate},
+	}
+
+	check := &apiCheck{name: "test", url: "https://example.api:1234", method: http.MethodGet}
+
+	gaugeObservers := check.getCertificateGaugeObservers(tlsState, "example.api")
+	require.Len(t, gaugeObservers, 3)
+
+	assert.Equal(t, certificateExpiryMetricName, gaugeObservers[0].(*float64GaugeObserver).name)
+	assert.Equal(t, certificateExpiryMetricName, gaugeObservers[1].(*float64GaugeObserver).name)
+	assert.Equal(t, certificateHostMatchMetricName, gaugeObservers[2].(*int64GaugeObserver).name)
+
+	assert.Equal(t, leafCertificateType, getCertificateType(tlsState.PeerCertificates, 0))
+	assert.Equal(t, rootCertificateType, getCertificateType(tlsState.PeerCertificates, 1))
+	assert.InDelta(t, 30, getCertificateExpiryDays(leaf), 0.1)
+}
+
+func TestGetCertificateHost(t *testing.T) {
+	assert.Equal(t, "example.api", getCertificateHost(&tls.ConnectionState{ServerName: "example.api"}, "127.0.0.1"))
+	assert.Equal(t, "127.0.0.1", getCertificateHost(&tls.ConnectionState{}, "127.0.0.1"))
+}
+
+func TestGetCertificateExpiringStatusGaugeObserver(t *testing.T) {
+	leaf, _ := createTestCertificate(t, "example.api", "example.api", time.Now().Add(10*24*time.Hour), nil, nil)
+	tlsState := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+
+	check := &apiCheck{name: "test", url: "https://example.api:1234", method: http.MethodGet}
+	assert.Nil(t, check.getCertificateExpiringStatusGaugeObserver(http.StatusOK, tlsState))
+
+	check.certificateExpiryThresholdDays = 7
+	assert.Nil(t, check.getCertificateExpiringStatusGaugeObserver(http.StatusOK, tlsState))
+
+	check.certificateExpiryThresholdDays = 14
+	statusGaugeObserver := check.getCertificateExpiringStatusGaugeObserver(http.StatusOK, tlsState)
+	require.NotNil(t, statusGaugeObserver)
+	assert.Equal(t, statusMetricName, statusGaugeObserver.name)
+
+	assert.Nil(t, check.getCertificateExpiringStatusGaugeObserver(http.StatusOK, nil))
+}
+
+func TestRun_CertificateExpiringSlowResponse(t *testing.T) {
+	leaf, leafKey := createTestCertificate(t, "example.api", "example.api", time.Now().Add(10*24*time.Hour), nil, nil)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.Raw}, PrivateKey: leafKey}}}
+	server.StartTLS()
+	defer server.Close()
+
+	check, err := newApiCheck(&checkConfig{
+		Name:                           "test",
+		URL:                            server.URL,
+		Method:                         http.MethodGet,
+		Timeout:                        "5s",
+		ExpectedStatusCode:             "200",
+		TLS:                            tlsConfig{InsecureSkipVerify: true},
+		SlowResponse:                   slowResponseConfig{Warning: "1ms"},
+		CertificateExpiryThresholdDays: 14,
+	})
+	require.NoError(t, err)
+
+	// The response is both slow and has an expiring certificate
+	result := getTestCheckResult(context.Background(), t, check)
+	assert.Equal(t, certificateExpiringStatusMetricStatusLabelValue, result.Status)
+	assert.Equal(t, "14", result.Labels[statusMetricCertificateExpiryThresholdDaysLabelName])
+	assert.NotContains(t, result.Labels, statusMetricSlowResponseSeverityLabelName)
+
+	// Without the threshold, the response is slow
+	check.certificateExpiryThresholdDays = 0
+	result = getTestCheckResult(context.Background(), t, check)
+	assert.Equal(t, slowResponseStatusMetricStatusLabelValue, result.Status)
+	assert.Equal(t, warningSlowResponseSeverityLabelValue, result.Labels[statusMetricSlowResponseSeverityLabelName])
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/number"
+)
+
+const (
+	checkCommandName        = "check"
+	configFlagName          = "config"
+	outputFlagName          = "output"
+	tableOutput             = "table"
+	jsonOutput              = "json"
+	errorCheckResultStatus  = "error"
+	checkCommandSuccessCode = 0
+	checkCommandFailureCode = 1
+	checkCommandUsageCode   = 2
+	missingCheckResultValue = "-"
+	tableColumnPadding      = 2
+)
+
+// The result of one check's run, from the values of its metrics
+type checkResult struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Status  string            `json:"status"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Metrics []*metricRecord   `json:"metrics,omitempty"`
+	metrics map[string]float64
+}
+
+// Columns of the table output, after the check name and status
+var checkResultTableMetrics = []struct {
+	header     string
+	metricName string
+}{
+	{"RESPONSE TIME (MS)", responseTimeMetricName},
+	{"DNS (MS)", dnsLookupTimeMetricName},
+	{"CONNECT (MS)", connectTimeMetricName},
+	{"TLS (MS)", tlsHandshakeTimeMetricName},
+	{"TTFB (MS)", timeToFirstByteMetricName},
+	{"DOWNLOAD (MS)", bodyDownloadTimeMetricName},
+	{"BODY (BYTES)", responseBodyLengthMetricName},
+}
+
+// Runs the checks once and prints their results, without sending metrics to Logz.io.
+// Returns the exit code: 0 if all checks succeeded, 1 if a check failed and 2 if the command is invalid.
+func runCheckCommand(args []string, output io.Writer, errorOutput io.Writer) int {
+	flags := flag.NewFlagSet(checkCommandName, flag.ContinueOnError)
+	flags.SetOutput(errorOutput)
+	configFile := flags.String(configFlagName, "", "path of a YAML/JSON checks file (default: the checks from the environment variables)")
+	outputFormat := flags.String(outputFlagName, tableOutput, fmt.Sprintf("output format: %s or %s", tableOutput, jsonOutput))
+
+	if err := flags.Parse(args); err != nil {
+		return checkCommandUsageCode
+	}
+
+	if *outputFormat != tableOutput && *outputFormat != jsonOutput {
+		_, _ = fmt.Fprintf(errorOutput, "%s must be %s or %s\n", outputFlagName, tableOutput, jsonOutput)
+		return checkCommandUsageCode
+	}
+
+	apiStatus, err := newCheckCommandApiStatus(*configFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(errorOutput, "Error getting api checks: %v\n", err)
+		return checkCommandUsageCode
+	}
+
+	// A check that failed to run has the error status, so the other checks' results are still printed
+	_, checkResults, checksErr := apiStatus.runChecks()
+	if checksErr != nil {
+		_, _ = fmt.Fprintf(errorOutput, "Error running checks: %v\n", checksErr)
+	}
+
+	if *outputFormat == jsonOutput {
+		err = printCheckResultsJSON(output, checkResults)
+	} else {
+		err = printCheckResultsTable(output, checkResults)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(errorOutput, "Error printing check results: %v\n", err)
+		return checkCommandFailureCode
+	}
+
+	for _, result := range checkResults {
+		if result.Status != successStatusMetricStatusLabelValue {
+			return checkCommandFailureCode
+		}
+	}
+
+	return checkCommandSuccessCode
+}
+
+// Returns an api status with the checks of the config file if set, otherwise with the checks from the environment variables
+func newCheckCommandApiStatus(configFile string) (*logzioApiStatus, error) {
+	var checks []*apiCheck
+	var err error
+
+	if configFile != "" {
+		config, err := readChecksConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+
+		checks, err = newApiChecks(config)
+		if err != nil {
+			return nil, err
+		}
+	} else if checks, err = getApiChecks(); err != nil {
+		return nil, err
+	}
+
+	checksConcurrency, err := getChecksConcurrency()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logzioApiStatus{
+		ctx:               context.Background(),
+		checks:            checks,
+		checksConcurrency: checksConcurrency,
+	}, nil
+}
+
+// Returns the check's result with the values of its run's gauge observers, or the error status if it did not run
+func (ac *apiCheck) newCheckResult(gaugeObservers []metricRegister) *checkResult {
+	result := &checkResult{
+		Name:    ac.name,
+		URL:     ac.getRedactedURL(),
+		Method:  ac.method,
+		Status:  errorCheckResultStatus,
+		metrics: make(map[string]float64),
+	}
+
+	result.addMetrics(gaugeObservers)
+
+	return result
+}
+
+// Adds the values of the check's gauge observers: the status with its labels, and the other metrics sorted by name
+func (cr *checkResult) addMetrics(gaugeObservers []metricRegister) {
+	for _, metricReg := range gaugeObservers {
+		record := getGaugeObserverRecord(metricReg)
+		if record == nil {
+			continue
+		}
+
+		for _, labelName := range []string{checkNameLabelName, urlLabelName, methodLabelName} {
+			delete(record.Labels, labelName)
+		}
+
+		if record.Name == statusMetricName {
+			cr.Status = record.Labels[statusMetricStatusLabelName]
+			delete(record.Labels, statusMetricStatusLabelName)
+			cr.Labels = record.Labels
+			continue
+		}
+
+		delete(record.Labels, unitLabelName)
+		cr.Metrics = append(cr.Metrics, record)
+		cr.metrics[record.Name] = record.Value
+	}
+
+	sort.SliceStable(cr.Metrics, func(i, j int) bool {
+		return cr.Metrics[i].Name < cr.Metrics[j].Name
+	})
+}
+
+// Returns the gauge observer's value and labels, where duplicate labels keep their last value like the meter's
+func getGaugeObserverRecord(metricReg metricRegister) *metricRecord {
+	switch gaugeObserver := metricReg.(type) {
+	case *int64GaugeObserver:
+		attributes := attribute.NewSet(gaugeObserver.attributes...)
+
+		return &metricRecord{
+			Name:        gaugeObserver.name,
+			Value:       float64(gaugeObserver.value),
+			Labels:      getLabels(attributes.Iter()),
+			attributes:  attributes.ToSlice(),
+			description: gaugeObserver.description,
+			numberKind:  number.Int64Kind,
+		}
+	case *float64GaugeObserver:
+		attributes := attribute.NewSet(gaugeObserver.attributes...)
+
+		return &metricRecord{
+			Name:        gaugeObserver.name,
+			Value:       gaugeObserver.value,
+			Labels:      getLabels(attributes.Iter()),
+			attributes:  attributes.ToSlice(),
+			description: gaugeObserver.description,
+			numberKind:  number.Float64Kind,
+		}
+	default:
+		return nil
+	}
+}
+
+func printCheckResultsJSON(output io.Writer, checkResults []*checkResult) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(checkResults)
+}
+
+// Prints a row per check with its status, timings and the labels of its status (the assertion results)
+func printCheckResultsTable(output io.Writer, checkResults []*checkResult) error {
+	writer := tabwriter.NewWriter(output, 0, 0, tableColumnPadding, ' ', 0)
+	headers := []string{"CHECK", "STATUS"}
+
+	for _, column := range checkResultTableMetrics {
+		headers = append(headers, column.header)
+	}
+
+	headers = append(headers, "DETAILS")
+	_, _ = fmt.Fprintln(writer, strings.Join(headers, "\t"))
+
+	for _, result := range checkResults {
+		row := []string{result.Name, result.Status}
+
+		for _, column := range checkResultTableMetrics {
+			value, ok := result.metrics[column.metricName]
+			if !ok {
+				row = append(row, missingCheckResultValue)
+				continue
+			}
+
+			row = append(row, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+
+		row = append(row, formatLabels(result.Labels))
+		_, _ = fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+// Formats labels as name=value pairs sorted by name
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+strconv.Quote(value))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, " ")
+}
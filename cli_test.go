@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestChecksFile(t *testing.T) string {
+	checksFile := filepath.Join(t.TempDir(), "checks.yaml")
+	err := os.WriteFile(checksFile, []byte(testChecksConfig), 0600)
+	require.NoError(t, err)
+
+	return checksFile
+}
+
+func TestRunCheckCommand_JSON(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusInternalServerError, "error"))
+
+	var output, errorOutput bytes.Buffer
+
+	exitCode := runCheckCommand([]string{"-config", writeTestChecksFile(t), "-output", "json"}, &output, &errorOutput)
+	assert.Equal(t, checkCommandFailureCode, exitCode)
+
+	var checkResults []*checkResult
+	err := json.Unmarshal(output.Bytes(), &checkResults)
+	require.NoError(t, err)
+	require.Len(t, checkResults, 2)
+
+	assert.Equal(t, "users", checkResults[0].Name)
+	assert.Equal(t, successStatusMetricStatusLabelValue, checkResults[0].Status)
+	assert.Equal(t, "200", checkResults[0].Labels[statusMetricResponseStatusCodeLabelName])
+
+	metricNames := make([]string, 0)
+	for _, metric := range checkResults[0].Metrics {
+		metricNames = append(metricNames, metric.Name)
+	}
+
+	assert.Equal(t, []string{responseBodyLengthMetricName, responseTimeMetricName}, metricNames)
+
+	assert.Equal(t, "orders", checkResults[1].Name)
+	assert.Equal(t, http.MethodPost, checkResults[1].Method)
+	assert.Equal(t, noMatchStatusCodeStatusMetricStatusLabelValue, checkResults[1].Status)
+	assert.Equal(t, "201,202", checkResults[1].Labels[statusMetricExpectedResponseStatusCodeLabelName])
+	assert.Equal(t, "500", checkResults[1].Labels[statusMetricResponseStatusCodeLabelName])
+}
+
+func TestRunCheckCommand_ResultsFromRuns(t *testing.T) {
+	err := os.Setenv(logLevelEnvName, "debug")
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+
+	err = setLoggers(&logs)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, setLoggers(os.Stdout))
+	}()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusCreated, "created"))
+
+	var output, errorOutput bytes.Buffer
+
+	exitCode := runCheckCommand([]string{"-config", writeTestChecksFile(t), "-output", "json"}, &output, &errorOutput)
+	assert.Equal(t, checkCommandSuccessCode, exitCode)
+
+	var checkResults []*checkResult
+	err = json.Unmarshal(output.Bytes(), &checkResults)
+	require.NoError(t, err)
+	require.Len(t, checkResults, 2)
+	assert.Equal(t, "201", checkResults[1].Labels[statusMetricResponseStatusCodeLabelName])
+	require.Len(t, checkResults[1].Metrics, 2)
+	assert.Equal(t, float64(len("created")), checkResults[1].Metrics[0].Value)
+
+	// The results are the values of the checks' runs, so their metrics are not collected
+	for _, record := range getLogRecords(t, &logs) {
+		assert.NotContains(t, record["msg"], "Observing")
+	}
+
+	os.Clearenv()
+}
+
+func TestRunCheckCommand_Table(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusCreated, ""))
+
+	var output, errorOutput bytes.Buffer
+
+	exitCode := runCheckCommand([]string{"-config", writeTestChecksFile(t)}, &output, &errorOutput)
+	assert.Equal(t, checkCommandSuccessCode, exitCode)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "CHECK"))
+	assert.Regexp(t, `^users\s+success\s+[\d.]+\s+-\s+-\s+-\s+-\s+-\s+7\s+response_status_code="200"$`, lines[1])
+	assert.Regexp(t, `^orders\s+success\s+[\d.]+\s+-\s+-\s+-\s+-\s+-\s+0\s+response_status_code="201"$`, lines[2])
+}
+
+func TestRunCheckCommand_EnvCheck(t *testing.T) {
+	err := os.Setenv(apiUrlEnvName, "https://example.api:1234")
+	require.NoError(t, err)
+	err = os.Setenv(methodEnvName, http.MethodGet)
+	require.NoError(t, err)
+	err = os.Setenv(apiResponseTimeoutEnvName, "10")
+	require.NoError(t, err)
+	err = os.Setenv(expectedStatusCodeEnvName, "200")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234",
+		httpmock.NewErrorResponder(errors.New("connection refused")))
+
+	var output, errorOutput bytes.Buffer
+
+	exitCode := runCheckCommand([]string{"-output", "json"}, &output, &errorOutput)
+	assert.Equal(t, checkCommandFailureCode, exitCode)
+
+	var checkResults []*checkResult
+	err = json.Unmarshal(output.Bytes(), &checkResults)
+	require.NoError(t, err)
+	require.Len(t, checkResults, 1)
+	assert.Equal(t, defaultCheckName, checkResults[0].Name)
+	assert.Equal(t, connectionFailedStatusMetricStatusLabelValue, checkResults[0].Status)
+	assert.Empty(t, checkResults[0].Metrics)
+
+	os.Clearenv()
+}
+
+func TestRunCheckCommand_BadArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{"-output", "xml"},
+		{"-config", filepath.Join(t.TempDir(), "missing.yaml")},
+		{"-unknown"},
+		{},
+	} {
+		var output, errorOutput bytes.Buffer
+
+		exitCode := runCheckCommand(args, &output, &errorOutput)
+		assert.Equal(t, checkCommandUsageCode, exitCode, args)
+		assert.Empty(t, output.String(), args)
+		assert.NotEmpty(t, errorOutput.String(), args)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	checksFileEnvName              = "CHECKS_FILE"
+	checksEnvName                  = "CHECKS"
+	checkNameEnvName               = "CHECK_NAME"
+	checkIntervalEnvName           = "CHECK_INTERVAL"
+	checkCronEnvName               = "CHECK_CRON"
+	defaultCheckName               = "default"
+	defaultCheckMethod             = http.MethodGet
+	connectTimeoutEnvName          = "CONNECT_TIMEOUT"
+	tlsHandshakeTimeoutEnvName     = "TLS_HANDSHAKE_TIMEOUT"
+	defaultCheckTimeout            = 10 * time.Second
+	defaultCheckExpectedStatusCode = "200"
+	defaultCheckInterval           = time.Minute
+)
+
+type checksConfig struct {
+	Checks []*checkConfig `yaml:"checks"`
+}
+
+type checkConfig struct {
+	Name                           string             `yaml:"name"`
+	URL                            string             `yaml:"url"`
+	Method                         string             `yaml:"method"`
+	Headers                        map[string]string  `yaml:"headers"`
+	Body                           string             `yaml:"body"`
+	Auth                           authConfig         `yaml:"auth"`
+	Timeout                        string             `yaml:"timeout"`
+	ConnectTimeout                 string             `yaml:"connect_timeout"`
+	TLSHandshakeTimeout            string             `yaml:"tls_handshake_timeout"`
+	TLS                            tlsConfig          `yaml:"tls"`
+	ExpectedStatusCode             string             `yaml:"expected_status_code"`
+	ExpectedBody                   *string            `yaml:"expected_body"`
+	HeaderAssertions               []*assertionConfig `yaml:"header_assertions"`
+	BodyAssertions                 []*assertionConfig `yaml:"body_assertions"`
+	SlowResponse                   slowResponseConfig `yaml:"slow_response"`
+	CertificateExpiryThresholdDays int                `yaml:"certificate_expiry_threshold_days"`
+	Interval                       string             `yaml:"interval"`
+	Cron                           string             `yaml:"cron"`
+	LabelValues                    labelValuesConfig  `yaml:"label_values"`
+	Retry                          retryConfig        `yaml:"retry"`
+}
+
+// Response time thresholds (Go durations) of the slow response status
+type slowResponseConfig struct {
+	Warning  string `yaml:"warning"`
+	Critical string `yaml:"critical"`
+}
+
+type authConfig struct {
+	BearerToken string `yaml:"bearer_token"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	// Instead of the bearer token and basic auth
+	OAuth2 *oauth2Config `yaml:"oauth2"`
+	SigV4  *sigV4Config  `yaml:"sigv4"`
+}
+
+// Returns the checks from the checks file (or inline checks) if set, otherwise a single check from the environment variables
+func getApiChecks() ([]*apiCheck, error) {
+	config, err := getChecksConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		envCheckConfig, err := getEnvCheckConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		check, err := newApiCheck(envCheckConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*apiCheck{check}, nil
+	}
+
+	return newApiChecks(config)
+}
+
+func newApiChecks(config *checksConfig) ([]*apiCheck, error) {
+	if len(config.Checks) == 0 {
+		return nil, fmt.Errorf("checks config must contain at least one check")
+	}
+
+	checks := make([]*apiCheck, 0, len(config.Checks))
+	checkNames := make(map[string]bool)
+
+	for index, checkConf := range config.Checks {
+		if checkConf == nil {
+			return nil, fmt.Errorf("check %d must not be empty", index)
+		}
+
+		if checkNames[checkConf.Name] {
+			return nil, fmt.Errorf("check name %s is not unique", checkConf.Name)
+		}
+
+		checkNames[checkConf.Name] = true
+		setCheckConfigDefaults(checkConf)
+
+		check, err := newApiCheck(checkConf)
+		if err != nil {
+			return nil, fmt.Errorf("error in check %d (%s): %v", index, checkConf.Name, err)
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
+
+func getChecksConfig() (*checksConfig, error) {
+	if checksFile := os.Getenv(checksFileEnvName); checksFile != "" {
+		return readChecksConfigFile(checksFile)
+	}
+
+	if checks := os.Getenv(checksEnvName); checks != "" {
+		return parseChecksConfig([]byte(checks))
+	}
+
+	return nil, nil
+}
+
+func readChecksConfigFile(checksFile string) (*checksConfig, error) {
+	debugLogger.Println("Reading checks file", checksFile)
+
+	checksBytes, err := os.ReadFile(checksFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading checks file %s: %v", checksFile, err)
+	}
+
+	return parseChecksConfig(checksBytes)
+}
+
+// Parses YAML or JSON checks config (JSON is valid YAML)
+func parseChecksConfig(checksBytes []byte) (*checksConfig, error) {
+	config := &checksConfig{}
+
+	if err := yaml.Unmarshal(checksBytes, config); err != nil {
+		return nil, fmt.Errorf("error parsing checks config: %v", err)
+	}
+
+	return config, nil
+}
+
+func setCheckConfigDefaults(config *checkConfig) {
+	if config.Method == "" {
+		config.Method = defaultCheckMethod
+	}
+
+	if config.Timeout == "" {
+		config.Timeout = defaultCheckTimeout.String()
+	}
+
+	if config.ExpectedStatusCode == "" {
+		config.ExpectedStatusCode = defaultCheckExpectedStatusCode
+	}
+}
+
+func getEnvCheckConfig() (*checkConfig, error) {
+	headers, err := getApiRequestHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("error getting api headers: %v", err)
+	}
+
+	certificateExpiryThresholdDays := 0
+
+	if certificateExpiryThresholdDaysString := os.Getenv(certificateExpiryThresholdDaysEnvName); certificateExpiryThresholdDaysString != "" {
+		certificateExpiryThresholdDays, err = strconv.Atoi(certificateExpiryThresholdDaysString)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", certificateExpiryThresholdDaysEnvName)
+		}
+	}
+
+	labelValueMaxLength := 0
+
+	if labelValueMaxLengthString := os.Getenv(labelValueMaxLengthEnvName); labelValueMaxLengthString != "" {
+		labelValueMaxLength, err = strconv.Atoi(labelValueMaxLengthString)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", labelValueMaxLengthEnvName)
+		}
+	}
+
+	retryAttempts := 0
+
+	if retryAttemptsString := os.Getenv(retryAttemptsEnvName); retryAttemptsString != "" {
+		retryAttempts, err = strconv.Atoi(retryAttemptsString)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", retryAttemptsEnvName)
+		}
+	}
+
+	tlsInsecureSkipVerify := false
+
+	if tlsInsecureSkipVerifyString := os.Getenv(tlsInsecureSkipVerifyEnvName); tlsInsecureSkipVerifyString != "" {
+		tlsInsecureSkipVerify, err = strconv.ParseBool(tlsInsecureSkipVerifyString)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", tlsInsecureSkipVerifyEnvName)
+		}
+	}
+
+	var oauth2 *oauth2Config
+
+	if os.Getenv(oauth2TokenURLEnvName) != "" || os.Getenv(oauth2ClientIDEnvName) != "" {
+		oauth2 = &oauth2Config{
+			TokenURL:     os.Getenv(oauth2TokenURLEnvName),
+			ClientID:     os.Getenv(oauth2ClientIDEnvName),
+			ClientSecret: os.Getenv(oauth2ClientSecretEnvName),
+			Scopes:       splitList(os.Getenv(oauth2ScopesEnvName)),
+			Audience:     os.Getenv(oauth2AudienceEnvName),
+			AuthStyle:    os.Getenv(oauth2AuthStyleEnvName),
+		}
+	}
+
+	var sigV4 *sigV4Config
+
+	// Requests are signed if the service is set, since it depends on the API (for example: execute-api or lambda)
+	if sigV4Service := os.Getenv(sigV4ServiceEnvName); sigV4Service != "" {
+		sigV4 = &sigV4Config{Service: sigV4Service, Region: os.Getenv(sigV4RegionEnvName)}
+	}
+
+	checkName := os.Getenv(checkNameEnvName)
+	if checkName == "" {
+		checkName = defaultCheckName
+	}
+
+	expectedResponseBody := os.Getenv(expectedBodyEnvName)
+	var expectedResponseBodyPointer *string
+	var bodyAssertions []*assertionConfig
+
+	// EXPECTED_BODY is matched exactly unless EXPECTED_BODY_MATCH sets another assertion type
+	if expectedBodyMatch := os.Getenv(expectedBodyMatchEnvName); expectedBodyMatch != "" && expectedBodyMatch != equalsAssertionType {
+		bodyAssertions = []*assertionConfig{{Type: expectedBodyMatch, Value: expectedResponseBody}}
+	} else {
+		expectedResponseBodyPointer = &expectedResponseBody
+	}
+
+	return &checkConfig{
+		Name:    checkName,
+		URL:     os.Getenv(apiUrlEnvName),
+		Method:  os.Getenv(methodEnvName),
+		Headers: headers,
+		Body:    os.Getenv(bodyEnvName),
+		Auth: authConfig{
+			BearerToken: os.Getenv(bearerTokenEnvName),
+			Username:    os.Getenv(usernameEnvName),
+			Password:    os.Getenv(passwordEnvName),
+			OAuth2:      oauth2,
+			SigV4:       sigV4,
+		},
+		Timeout:             os.Getenv(apiResponseTimeoutEnvName),
+		ConnectTimeout:      os.Getenv(connectTimeoutEnvName),
+		TLSHandshakeTimeout: os.Getenv(tlsHandshakeTimeoutEnvName),
+		TLS: tlsConfig{
+			ClientCert:         os.Getenv(tlsClientCertEnvName),
+			ClientCertFile:     os.Getenv(tlsClientCertFileEnvName),
+			ClientKey:          os.Getenv(tlsClientKeyEnvName),
+			ClientKeyFile:      os.Getenv(tlsClientKeyFileEnvName),
+			CABundle:           os.Getenv(tlsCABundleEnvName),
+			CABundleFile:       os.Getenv(tlsCABundleFileEnvName),
+			InsecureSkipVerify: tlsInsecureSkipVerify,
+			ServerName:         os.Getenv(tlsServerNameEnvName),
+			MinVersion:         os.Getenv(tlsMinVersionEnvName),
+		},
+		ExpectedStatusCode: os.Getenv(expectedStatusCodeEnvName),
+		ExpectedBody:       expectedResponseBodyPointer,
+		BodyAssertions:     bodyAssertions,
+		SlowResponse: slowResponseConfig{
+			Warning: os.Getenv(slowResponseThresholdEnvName),
+		},
+		CertificateExpiryThresholdDays: certificateExpiryThresholdDays,
+		Interval:                       os.Getenv(checkIntervalEnvName),
+		Cron:                           os.Getenv(checkCronEnvName),
+		LabelValues: labelValuesConfig{
+			ResponseBody: os.Getenv(responseBodyLabelModeEnvName),
+			Error:        os.Getenv(errorLabelModeEnvName),
+			MaxLength:    labelValueMaxLength,
+		},
+		Retry: retryConfig{
+			Attempts:  retryAttempts,
+			Backoff:   os.Getenv(retryBackoffEnvName),
+			Retryable: splitList(os.Getenv(retryableEnvName)),
+		},
+	}, nil
+}
+
+// Parses a Go duration (for example: 500ms, 2s), where empty means not set
+func parseOptionalDuration(durationString string) (time.Duration, error) {
+	if durationString == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(durationString)
+	if err != nil {
+		return 0, err
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration %s must be positive", durationString)
+	}
+
+	return duration, nil
+}
+
+// Parses a Go duration (for example: 500ms, 2s), or a number of seconds as in older configs, where empty means not set
+func parseOptionalTimeout(timeoutString string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(timeoutString, 64); err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		if seconds <= 0 {
+			return 0, fmt.Errorf("timeout %s must be positive", timeoutString)
+		}
+
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	return parseOptionalDuration(timeoutString)
+}
+
+// Checks that OAuth2 and SigV4 are not set together or with the bearer token and basic auth, since each of them sets the
+// Authorization header
+func validateAuthConfig(config authConfig) error {
+	authMethods := 0
+	staticAuth := config.BearerToken != "" || config.Username != "" || config.Password != ""
+
+	for _, isSet := range []bool{staticAuth, config.OAuth2 != nil, config.SigV4 != nil} {
+		if isSet {
+			authMethods++
+		}
+	}
+
+	if authMethods > 1 {
+		return fmt.Errorf("only one of bearer token or basic auth, OAuth2 and SigV4 can be set")
+	}
+
+	return nil
+}
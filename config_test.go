@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChecksConfig = `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+    headers:
+      Accept: application/json
+    auth:
+      bearer_token: token
+    timeout: 5
+    expected_body: success
+    body_assertions:
+      - type: contains
+        value: succ
+  - name: orders
+    url: https://example.api:1234/orders
+    method: POST
+    body: test
+    expected_status_code: 201,202
+`
+
+func TestNewLogzioApiStatus_ChecksFile(t *testing.T) {
+	checksFile := filepath.Join(t.TempDir(), "checks.yaml")
+	err := os.WriteFile(checksFile, []byte(testChecksConfig), 0600)
+	require.NoError(t, err)
+
+	err = os.Setenv(checksFileEnvName, checksFile)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+	require.NotNil(t, apiStatus)
+	require.Len(t, apiStatus.checks, 2)
+
+	usersCheck := apiStatus.checks[0]
+	assert.Equal(t, "users", usersCheck.name)
+	assert.Equal(t, "https://example.api:1234/users", usersCheck.url)
+	assert.Equal(t, http.MethodGet, usersCheck.method)
+	assert.Equal(t, map[string]string{"Accept": "application/json"}, usersCheck.headers)
+	assert.Equal(t, "token", usersCheck.bearerToken)
+	assert.Equal(t, 5*time.Second, usersCheck.responseTimeout)
+	assert.Equal(t, defaultCheckExpectedStatusCode, usersCheck.expectedResponseStatusCode.expression)
+	require.Len(t, usersCheck.responseBodyAssertions, 2)
+	assert.Equal(t, equalsAssertionType, usersCheck.responseBodyAssertions[0].assertionType)
+	assert.Equal(t, "success", usersCheck.responseBodyAssertions[0].value)
+	assert.Equal(t, containsAssertionType, usersCheck.responseBodyAssertions[1].assertionType)
+	assert.Equal(t, "succ", usersCheck.responseBodyAssertions[1].value)
+
+	ordersCheck := apiStatus.checks[1]
+	assert.Equal(t, "orders", ordersCheck.name)
+	assert.Equal(t, http.MethodPost, ordersCheck.method)
+	assert.Equal(t, "test", ordersCheck.body)
+	assert.Equal(t, defaultCheckTimeout, ordersCheck.responseTimeout)
+	assert.Equal(t, "201,202", ordersCheck.expectedResponseStatusCode.expression)
+	assert.Empty(t, ordersCheck.responseBodyAssertions)
+
+	os.Clearenv()
+}
+
+func TestNewLogzioApiStatus_InlineJSONChecks(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+	require.Len(t, apiStatus.checks, 1)
+	assert.Equal(t, "users", apiStatus.checks[0].name)
+	assert.Equal(t, "https://example.api:1234/users", apiStatus.checks[0].url)
+
+	os.Clearenv()
+}
+
+func TestNewLogzioApiStatus_ChecksNotUnique(t *testing.T) {
+	err := os.Setenv(checksEnvName, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+  - name: users
+    url: https://example.api:1234/orders
+`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.Error(t, err)
+
+	os.Clearenv()
+}
+
+func TestNewLogzioApiStatus_CheckNoName(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": [{"url": "https://example.api:1234/users"}]}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.Error(t, err)
+
+	os.Clearenv()
+}
+
+func TestNewLogzioApiStatus_NoChecks(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": []}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.Error(t, err)
+
+	os.Clearenv()
+}
+
+func TestRun_MultipleChecks(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(checksEnvName, testChecksConfig)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusInternalServerError, "error"))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			require.NotNil(t, metrics)
+
+			assert.Len(t, metrics, 6)
+
+			statuses := make(map[string]interface{})
+
+			for _, metric := range metrics {
+				if metric["__name__"] == statusMetricName {
+					statuses[metric[checkNameLabelName].(string)] = metric[statusMetricStatusLabelName]
+				}
+			}
+
+			assert.Equal(t, map[string]interface{}{
+				"users":  successStatusMetricStatusLabelValue,
+				"orders": noMatchStatusCodeStatusMetricStatusLabelValue,
+			}, statuses)
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	os.Clearenv()
+}
+
+func TestNewLogzioApiStatus_BadSlowResponseThresholds(t *testing.T) {
+	for _, slowResponse := range []string{
+		`{"warning": "2s", "critical": "1s"}`,
+		`{"warning": "fast"}`,
+		`{"critical": "-1s"}`,
+	} {
+		err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users", "slow_response": `+slowResponse+`}]}`)
+		require.NoError(t, err)
+
+		err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+		require.NoError(t, err)
+
+		err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+		require.NoError(t, err)
+
+		_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+		require.Error(t, err, slowResponse)
+
+		os.Clearenv()
+	}
+}
+
+func TestParseOptionalTimeout(t *testing.T) {
+	for timeoutString, expectedTimeout := range map[string]time.Duration{
+		"":      0,
+		"10":    10 * time.Second,
+		"1.5":   1500 * time.Millisecond,
+		"500ms": 500 * time.Millisecond,
+		"2s":    2 * time.Second,
+	} {
+		timeout, err := parseOptionalTimeout(timeoutString)
+		require.NoError(t, err, timeoutString)
+		assert.Equal(t, expectedTimeout, timeout, timeoutString)
+	}
+
+	for _, timeoutString := range []string{"0", "-1", "-1s", "NaN", "Inf", "fast"} {
+		_, err := parseOptionalTimeout(timeoutString)
+		assert.Error(t, err, timeoutString)
+	}
+}
+
+func TestNewLogzioApiStatus_CheckTimeouts(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users", "timeout": "500ms", "connect_timeout": "100ms", "tls_handshake_timeout": "200ms"}]}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(metricsExportersEnvName, stdoutMetricsExporterName)
+	require.NoError(t, err)
+
+	apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+	require.Len(t, apiStatus.checks, 1)
+
+	check := apiStatus.checks[0]
+	assert.Equal(t, 500*time.Millisecond, check.responseTimeout)
+	assert.Equal(t, 100*time.Millisecond, check.connectTimeout)
+	assert.Equal(t, 200*time.Millisecond, check.tlsHandshakeTimeout)
+	assert.NotNil(t, check.transport)
+
+	os.Clearenv()
+
+	for _, timeouts := range []string{
+		`"timeout": "0s"`,
+		`"connect_timeout": "fast"`,
+		`"tls_handshake_timeout": "-1s"`,
+	} {
+		err = os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users", `+timeouts+`}]}`)
+		require.NoError(t, err)
+
+		err = os.Setenv(metricsExportersEnvName, stdoutMetricsExporterName)
+		require.NoError(t, err)
+
+		_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+		require.Error(t, err, timeouts)
+
+		os.Clearenv()
+	}
+}
+
+func TestGetEnvCheckConfig_TLS(t *testing.T) {
+	err := os.Setenv(tlsCABundleFileEnvName, "/etc/ssl/private-ca.pem")
+	require.NoError(t, err)
+
+	err = os.Setenv(tlsInsecureSkipVerifyEnvName, "true")
+	require.NoError(t, err)
+
+	err = os.Setenv(tlsServerNameEnvName, "example.api")
+	require.NoError(t, err)
+
+	err = os.Setenv(tlsMinVersionEnvName, "1.2")
+	require.NoError(t, err)
+
+	config, err := getEnvCheckConfig()
+	require.NoError(t, err)
+	assert.Equal(t, tlsConfig{CABundleFile: "/etc/ssl/private-ca.pem", InsecureSkipVerify: true, ServerName: "example.api", MinVersion: "1.2"}, config.TLS)
+
+	err = os.Setenv(tlsInsecureSkipVerifyEnvName, "sometimes")
+	require.NoError(t, err)
+
+	_, err = getEnvCheckConfig()
+	require.Error(t, err)
+
+	os.Clearenv()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cronFieldsCount = 5
+
+// A standard 5 fields cron expression (minute, hour, day of month, month, day of week).
+// Fields support *, values, ranges (1-5), steps (*/15, 0-30/10) and lists of them separated by comma.
+type cronSchedule struct {
+	expression    string
+	minutes       map[int]bool
+	hours         map[int]bool
+	daysOfMonth   map[int]bool
+	months        map[int]bool
+	daysOfWeek    map[int]bool
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func newCronSchedule(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != cronFieldsCount {
+		return nil, fmt.Errorf("cron expression %s must have %d fields", expression, cronFieldsCount)
+	}
+
+	values := make([]map[int]bool, cronFieldsCount)
+
+	for index, field := range fields {
+		fieldValues, err := parseCronField(field, cronFields[index])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing cron expression %s: %v", expression, err)
+		}
+
+		values[index] = fieldValues
+	}
+
+	// Sunday is both 0 and 7
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return &cronSchedule{
+		expression:    expression,
+		minutes:       values[0],
+		hours:         values[1],
+		daysOfMonth:   values[2],
+		months:        values[3],
+		daysOfWeek:    values[4],
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, fieldRange cronField) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, term := range strings.Split(field, ",") {
+		step := 1
+
+		if strings.Contains(term, "/") {
+			termAndStep := strings.SplitN(term, "/", 2)
+
+			var err error
+			if step, err = strconv.Atoi(termAndStep[1]); err != nil || step < 1 {
+				return nil, fmt.Errorf("%s step %s must be a positive number", fieldRange.name, termAndStep[1])
+			}
+
+			term = termAndStep[0]
+		}
+
+		from, to := fieldRange.min, fieldRange.max
+
+		if term != "*" {
+			bounds := strings.SplitN(term, "-", 2)
+
+			var err error
+			if from, err = parseCronValue(bounds[0], fieldRange); err != nil {
+				return nil, err
+			}
+
+			to = from
+			if len(bounds) == 2 {
+				if to, err = parseCronValue(bounds[1], fieldRange); err != nil {
+					return nil, err
+				}
+			}
+
+			if from > to {
+				return nil, fmt.Errorf("%s range %s must be ascending", fieldRange.name, term)
+			}
+		}
+
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func parseCronValue(valueString string, fieldRange cronField) (int, error) {
+	value, err := strconv.Atoi(valueString)
+	if err != nil {
+		return 0, fmt.Errorf("%s value %s must be a number", fieldRange.name, valueString)
+	}
+
+	if value < fieldRange.min || value > fieldRange.max {
+		return 0, fmt.Errorf("%s value %d must be between %d and %d (inclusive)", fieldRange.name, value, fieldRange.min, fieldRange.max)
+	}
+
+	return value, nil
+}
+
+// Returns the first time after the given time that matches the schedule
+func (cs *cronSchedule) next(after time.Time) time.Time {
+	next := after.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule matches at least once in 4 years (for example: February 29th)
+	limit := next.AddDate(4, 0, 1)
+
+	for next.Before(limit) {
+		if !cs.months[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !cs.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !cs.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !cs.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+
+		return next
+	}
+
+	return time.Time{}
+}
+
+// Like standard cron, when both day of month and day of week are restricted, either of them matches
+func (cs *cronSchedule) matchesDay(date time.Time) bool {
+	dayOfMonth := cs.daysOfMonth[date.Day()]
+	dayOfWeek := cs.daysOfWeek[int(date.Weekday())]
+
+	if cs.anyDayOfMonth || cs.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
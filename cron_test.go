@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	after := time.Date(2022, time.March, 15, 10, 7, 30, 0, time.UTC) // Tuesday
+
+	expressions := map[string]time.Time{
+		"* * * * *":        time.Date(2022, time.March, 15, 10, 8, 0, 0, time.UTC),
+		"*/15 * * * *":     time.Date(2022, time.March, 15, 10, 15, 0, 0, time.UTC),
+		"0 * * * *":        time.Date(2022, time.March, 15, 11, 0, 0, 0, time.UTC),
+		"30 9 * * *":       time.Date(2022, time.March, 16, 9, 30, 0, 0, time.UTC),
+		"0 0 1 * *":        time.Date(2022, time.April, 1, 0, 0, 0, 0, time.UTC),
+		"0 12 * * 1-5":     time.Date(2022, time.March, 15, 12, 0, 0, 0, time.UTC),
+		"0 12 * * 0":       time.Date(2022, time.March, 20, 12, 0, 0, 0, time.UTC),
+		"0 12 * * 7":       time.Date(2022, time.March, 20, 12, 0, 0, 0, time.UTC),
+		"0 0 1 1 *":        time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":       time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 20 * 3":       time.Date(2022, time.March, 16, 0, 0, 0, 0, time.UTC),
+		"5,10 10-12 * * *": time.Date(2022, time.March, 15, 10, 10, 0, 0, time.UTC),
+	}
+
+	for expression, expected := range expressions {
+		schedule, err := newCronSchedule(expression)
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, schedule.next(after), expression)
+	}
+}
+
+func TestCronSchedule_NeverMatches(t *testing.T) {
+	schedule, err := newCronSchedule("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.next(time.Now()).IsZero())
+}
+
+func TestNewCronSchedule_BadExpression(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "10-5 * * * *", "a * * * *", "1, * * * *"} {
+		_, err := newCronSchedule(expression)
+		assert.Error(t, err, expression)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/cfn"
+)
+
+const (
+	failStackOnCheckFailureEnvName = "FAIL_STACK_ON_CHECK_FAILURE"
+	defaultCustomResourceIDPrefix  = "logzio-api-status"
+	checksCustomResourceDataKey    = "Checks"
+	succeededCustomResourceDataKey = "Succeeded"
+	failedCustomResourceDataKey    = "Failed"
+	statusesCustomResourceDataKey  = "Statuses"
+	// CloudFormation fails responses larger than 4KB
+	maxCustomResourceStatusesLength = 3 * 1024
+)
+
+// Handles the custom resource's lifecycle: creating or updating it validates the configuration by running the checks,
+// so a bad configuration fails the stack, and deleting it does nothing
+func customResourceRun(ctx context.Context, event cfn.Event) (string, map[string]interface{}, error) {
+	physicalResourceID := getCustomResourcePhysicalID(event)
+	infoLogger.Printf("Handling custom resource %s request for %s...\n", event.RequestType, physicalResourceID)
+
+	switch event.RequestType {
+	case cfn.RequestCreate, cfn.RequestUpdate:
+		data, err := runValidationChecks(ctx)
+		if err != nil {
+			errorLogger.Printf("Error validating checks: %v\n", err)
+		}
+
+		return physicalResourceID, data, err
+	case cfn.RequestDelete:
+		return physicalResourceID, nil, nil
+	default:
+		return physicalResourceID, nil, fmt.Errorf("unknown custom resource request type %s", event.RequestType)
+	}
+}
+
+// Returns the physical ID CloudFormation already has, so updates never replace the resource, or an ID from the
+// function's name and the resource's logical ID on create
+func getCustomResourcePhysicalID(event cfn.Event) string {
+	if event.PhysicalResourceID != "" {
+		return event.PhysicalResourceID
+	}
+
+	prefix := os.Getenv(awsLambdaFunctionNameEnvName)
+	if prefix == "" {
+		prefix = defaultCustomResourceIDPrefix
+	}
+
+	return fmt.Sprintf("%s-%s", prefix, event.LogicalResourceID)
+}
+
+// Runs the checks and sends their metrics, and returns the checks' outcome as the custom resource's data. Unlike a
+// scheduled invocation, it does not ship the checks' logs, update their states or send notifications, so a stack
+// operation does not count as a run. Fails if the configuration is invalid, a check failed to run or the metrics were
+// not sent. A check whose status is not success fails it only if FAIL_STACK_ON_CHECK_FAILURE is true.
+func runValidationChecks(ctx context.Context) (map[string]interface{}, error) {
+	failOnCheckFailure, err := getFailStackOnCheckFailure()
+	if err != nil {
+		return nil, err
+	}
+
+	setRegionLocation()
+	apiStatus, err := newLogzioApiStatus(ctx, lambdaMode)
+	if err != nil {
+		return nil, fmt.Errorf("error creating logzioApiStatus instance: %v", err)
+	}
+
+	gaugeObservers, checkResults, _, checksErr := apiStatus.runChecksOnly()
+
+	if err = apiStatus.collectMetrics(gaugeObservers); err != nil {
+		return nil, err
+	}
+
+	data := getCustomResourceData(checkResults)
+	if checksErr != nil {
+		return data, checksErr
+	}
+
+	if failOnCheckFailure && data[failedCustomResourceDataKey] != "0" {
+		return data, fmt.Errorf("%s checks did not succeed: %s", data[failedCustomResourceDataKey], data[statusesCustomResourceDataKey])
+	}
+
+	return data, nil
+}
+
+func getFailStackOnCheckFailure() (bool, error) {
+	failOnCheckFailureString := os.Getenv(failStackOnCheckFailureEnvName)
+	if failOnCheckFailureString == "" {
+		return false, nil
+	}
+
+	failOnCheckFailure, err := strconv.ParseBool(failOnCheckFailureString)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false: %v", failStackOnCheckFailureEnvName, err)
+	}
+
+	return failOnCheckFailure, nil
+}
+
+// Returns the number of checks, how many of them succeeded or not, and the status of each check (name=status, by name)
+func getCustomResourceData(checkResults []*checkResult) map[string]interface{} {
+	statuses := make([]string, 0, len(checkResults))
+	succeeded := 0
+
+	for _, result := range checkResults {
+		statuses = append(statuses, fmt.Sprintf("%s=%s", result.Name, result.Status))
+
+		if result.Status == successStatusMetricStatusLabelValue {
+			succeeded++
+		}
+	}
+
+	sort.Strings(statuses)
+	statusesString, _ := truncateString(strings.Join(statuses, ","), maxCustomResourceStatusesLength)
+
+	return map[string]interface{}{
+		checksCustomResourceDataKey:    strconv.Itoa(len(checkResults)),
+		succeededCustomResourceDataKey: strconv.Itoa(succeeded),
+		failedCustomResourceDataKey:    strconv.Itoa(len(checkResults) - succeeded),
+		statusesCustomResourceDataKey:  statusesString,
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/cfn"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCustomResourceResponseURL = "https://cloudformation-custom-resource-response-useast1.s3.amazonaws.com/response"
+
+func getTestCustomResourceResponse(t *testing.T, event cfn.Event) *cfn.Response {
+	var response *cfn.Response
+
+	httpmock.RegisterResponder(http.MethodPut, testCustomResourceResponseURL,
+		func(request *http.Request) (*http.Response, error) {
+			response = &cfn.Response{}
+			require.NoError(t, json.NewDecoder(request.Body).Decode(response))
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	event.RequestID = "unique-request-id"
+	event.ResponseURL = testCustomResourceResponseURL
+	event.LogicalResourceID = "PrimerInvoke"
+	event.StackID = "arn:aws:cloudformation:us-east-1:123456789012:stack/api-status/guid"
+
+	_, err := HandleRequest(context.Background(), event)
+	require.NoError(t, err)
+	require.NotNil(t, response)
+
+	return response
+}
+
+func setTestCustomResourceEnv(t *testing.T, checks string) {
+	err := os.Setenv(checksEnvName, checks)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "api-status")
+	require.NoError(t, err)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+}
+
+func TestHandleRequest_CustomResourceCreate(t *testing.T) {
+	setTestCustomResourceEnv(t, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+  - name: orders
+    url: https://example.api:1234/orders
+`)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusNotFound, ""))
+
+	response := getTestCustomResourceResponse(t, cfn.Event{RequestType: cfn.RequestCreate})
+	assert.Equal(t, cfn.StatusSuccess, response.Status)
+	assert.Equal(t, "api-status-PrimerInvoke", response.PhysicalResourceID)
+	assert.Equal(t, "unique-request-id", response.RequestID)
+	assert.Equal(t, map[string]interface{}{
+		checksCustomResourceDataKey:    "2",
+		succeededCustomResourceDataKey: "1",
+		failedCustomResourceDataKey:    "1",
+		statusesCustomResourceDataKey:  "orders=no_match_status_code,users=success",
+	}, response.Data)
+
+	// Updates keep the physical ID, so CloudFormation does not replace the resource
+	response = getTestCustomResourceResponse(t, cfn.Event{RequestType: cfn.RequestUpdate, PhysicalResourceID: "api-status-PrimerInvoke"})
+	assert.Equal(t, cfn.StatusSuccess, response.Status)
+	assert.Equal(t, "api-status-PrimerInvoke", response.PhysicalResourceID)
+
+	callCounts := httpmock.GetCallCountInfo()
+	assert.Equal(t, 2, callCounts["GET https://example.api:1234/users"])
+
+	os.Clearenv()
+}
+
+func TestHandleRequest_CustomResourceFailed(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	for _, checks := range []string{
+		`{"checks": [{"name": "users", "url": "https://example.api:1234/users", "expected_status_code": "600"}]}`,
+		`{"checks": [{"name": "users", "url": "https://example.api:1234/users\n"}]}`,
+	} {
+		setTestCustomResourceEnv(t, checks)
+
+		for _, requestType := range []cfn.RequestType{cfn.RequestCreate, cfn.RequestUpdate} {
+			response := getTestCustomResourceResponse(t, cfn.Event{RequestType: requestType})
+			assert.Equal(t, cfn.StatusFailed, response.Status, checks)
+			assert.NotEmpty(t, response.Reason, checks)
+			assert.Equal(t, "api-status-PrimerInvoke", response.PhysicalResourceID)
+		}
+
+		os.Clearenv()
+	}
+
+	response := getTestCustomResourceResponse(t, cfn.Event{RequestType: "Replace"})
+	assert.Equal(t, cfn.StatusFailed, response.Status)
+	assert.Contains(t, response.Reason, "Replace")
+}
+
+func TestHandleRequest_CustomResourceMetricsNotSent(t *testing.T) {
+	setTestCustomResourceEnv(t, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		httpmock.NewStringResponder(http.StatusUnauthorized, "invalid token"))
+
+	response := getTestCustomResourceResponse(t, cfn.Event{RequestType: cfn.RequestCreate})
+	assert.Equal(t, cfn.StatusFailed, response.Status)
+	assert.Contains(t, response.Reason, "error sending metrics")
+	assert.Contains(t, response.Reason, "401")
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["POST https://listener.logz.io:8053"])
+
+	os.Clearenv()
+}
+
+func TestHandleRequest_CustomResourceNoSideEffects(t *testing.T) {
+	setTestCustomResourceEnv(t, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	for name, value := range map[string]string{
+		notifiersEnvName:          "[{url: https://hooks.example.com/api-status}]",
+		stateStoreEnvName:         fileStateStoreType,
+		stateFileEnvName:          stateFile,
+		logzioLogsListenerEnvName: "https://listener.logz.io:8071",
+		logzioLogsTokenEnvName:    "123456789a",
+	} {
+		err := os.Setenv(name, value)
+		require.NoError(t, err)
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusInternalServerError, ""))
+	httpmock.RegisterResponder(http.MethodPost, "https://hooks.example.com/api-status",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+	httpmock.RegisterResponder(http.MethodPost, `=~^https://listener\.logz\.io:8071`,
+		httpmock.NewStringResponder(http.StatusOK, ""))
+
+	// Validating the stack is not a scheduled run: its checks' logs are not shipped, and their states and notifications
+	// are left to the scheduled invocations
+	response := getTestCustomResourceResponse(t, cfn.Event{RequestType: cfn.RequestCreate})
+	assert.Equal(t, cfn.StatusSuccess, response.Status)
+	assert.Equal(t, "users=no_match_status_code", response.Data[statusesCustomResourceDataKey])
+
+	callCounts := httpmock.GetCallCountInfo()
+	assert.Equal(t, 1, callCounts["GET https://example.api:1234/users"])
+	assert.Equal(t, 0, callCounts["POST https://hooks.example.com/api-status"])
+	assert.Equal(t, 0, callCounts[`POST =~^https://listener\.logz\.io:8071`])
+	assert.NoFileExists(t, stateFile)
+
+	os.Clearenv()
+}
+
+func TestHandleRequest_CustomResourceFailStackOnCheckFailure(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusNotFound, ""))
+
+	// By default only an invalid configuration fails the stack, so an unhealthy API can be monitored from its first deploy
+	for failStackOnCheckFailure, expectedStatus := range map[string]cfn.StatusType{
+		"":      cfn.StatusSuccess,
+		"false": cfn.StatusSuccess,
+		"true":  cfn.StatusFailed,
+		"maybe": cfn.StatusFailed,
+	} {
+		setTestCustomResourceEnv(t, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+  - name: orders
+    url: https://example.api:1234/orders
+`)
+
+		err := os.Setenv(failStackOnCheckFailureEnvName, failStackOnCheckFailure)
+		require.NoError(t, err)
+
+		response := getTestCustomResourceResponse(t, cfn.Event{RequestType: cfn.RequestCreate})
+		assert.Equal(t, expectedStatus, response.Status, failStackOnCheckFailure)
+
+		os.Clearenv()
+	}
+
+	// A stack that failed on the checks' statuses has them in its reason
+	setTestCustomResourceEnv(t, `{"checks": [{"name": "orders", "url": "https://example.api:1234/orders"}]}`)
+
+	err := os.Setenv(failStackOnCheckFailureEnvName, "true")
+	require.NoError(t, err)
+
+	response := getTestCustomResourceResponse(t, cfn.Event{RequestType: cfn.RequestUpdate, PhysicalResourceID: "api-status-PrimerInvoke"})
+	assert.Equal(t, cfn.StatusFailed, response.Status)
+	assert.Contains(t, response.Reason, "orders=no_match_status_code")
+
+	os.Clearenv()
+}
+
+func TestHandleRequest_CustomResourceDelete(t *testing.T) {
+	// Deleting succeeds even if the configuration is invalid
+	setTestCustomResourceEnv(t, `{"checks": []}`)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	response := getTestCustomResourceResponse(t, cfn.Event{RequestType: cfn.RequestDelete, PhysicalResourceID: "api-status-PrimerInvoke"})
+	assert.Equal(t, cfn.StatusSuccess, response.Status)
+	assert.Equal(t, "api-status-PrimerInvoke", response.PhysicalResourceID)
+	assert.Empty(t, response.Data)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+
+	os.Clearenv()
+}
+
+func TestHandleRequest_CustomResourceResponseFailed(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodPut, testCustomResourceResponseURL,
+		httpmock.NewStringResponder(http.StatusForbidden, "expired"))
+
+	_, err := HandleRequest(context.Background(), cfn.Event{
+		RequestType:        cfn.RequestDelete,
+		RequestID:          "unique-request-id",
+		ResponseURL:        testCustomResourceResponseURL,
+		PhysicalResourceID: "api-status-PrimerInvoke",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error sending custom resource response")
+}
+
+func TestGetCustomResourcePhysicalID(t *testing.T) {
+	assert.Equal(t, "logzio-api-status-PrimerInvoke", getCustomResourcePhysicalID(cfn.Event{LogicalResourceID: "PrimerInvoke"}))
+	assert.Equal(t, "api-status-PrimerInvoke", getCustomResourcePhysicalID(cfn.Event{LogicalResourceID: "PrimerInvoke", PhysicalResourceID: "api-status-PrimerInvoke"}))
+}
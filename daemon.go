@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+)
+
+// Runs the checks on their schedules and keeps exporting their latest results, until the context is done
+type apiStatusDaemon struct {
+	apiStatus            *logzioApiStatus
+	cont                 *controller.Controller
+	meter                metric.Meter
+	checksSemaphore      chan struct{}
+	lock                 sync.Mutex
+	checksGaugeObservers map[string][]metricRegister
+	registeredMetrics    map[string]bool
+	metricsListener      net.Listener
+}
+
+// The metrics endpoint is served only if the metrics listen address is not empty
+func newApiStatusDaemon(apiStatus *logzioApiStatus, metricsListenAddress string) (*apiStatusDaemon, error) {
+	var metricsListener net.Listener
+	var err error
+
+	if metricsListenAddress != "" {
+		if metricsListener, err = net.Listen("tcp", metricsListenAddress); err != nil {
+			return nil, fmt.Errorf("error listening on %s: %v", metricsListenAddress, err)
+		}
+	}
+
+	cont, err := apiStatus.createController()
+	if err != nil {
+		if metricsListener != nil {
+			_ = metricsListener.Close()
+		}
+
+		return nil, fmt.Errorf("error creating controller: %v", err)
+	}
+
+	return &apiStatusDaemon{
+		apiStatus:            apiStatus,
+		cont:                 cont,
+		meter:                cont.Meter(meterName),
+		checksSemaphore:      make(chan struct{}, apiStatus.checksConcurrency),
+		checksGaugeObservers: make(map[string][]metricRegister),
+		registeredMetrics:    make(map[string]bool),
+		metricsListener:      metricsListener,
+	}, nil
+}
+
+// Returns the next time the check should run after its last run
+func (ac *apiCheck) getNextRunTime(lastRunTime time.Time) time.Time {
+	if ac.cronSchedule != nil {
+		return ac.cronSchedule.next(lastRunTime)
+	}
+
+	return lastRunTime.Add(ac.interval)
+}
+
+// Runs the checks until the context is done, then waits for running checks and exports their last results
+func (asd *apiStatusDaemon) run(ctx context.Context) error {
+	var waitGroup sync.WaitGroup
+
+	if asd.metricsListener != nil {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+			servePrometheusMetrics(ctx, asd.metricsListener, newPrometheusMetricsHandler(asd.getGaugeObservers))
+		}()
+	}
+
+	for _, check := range asd.apiStatus.checks {
+		waitGroup.Add(1)
+
+		go func(check *apiCheck) {
+			defer waitGroup.Done()
+			asd.scheduleCheck(ctx, check)
+		}(check)
+	}
+
+	waitGroup.Wait()
+	infoLogger.Println("Exporting last results...")
+
+	if err := asd.cont.Stop(asd.apiStatus.ctx); err != nil {
+		return fmt.Errorf("error stopping controller: %v", err)
+	}
+
+	return nil
+}
+
+func (asd *apiStatusDaemon) scheduleCheck(ctx context.Context, check *apiCheck) {
+	// Interval checks run on start, cron checks wait for their first match
+	nextRunTime := time.Now()
+	if check.cronSchedule != nil {
+		nextRunTime = check.getNextRunTime(nextRunTime)
+	}
+
+	for {
+		debugLogger.Printf("Next run of check %s at %s\n", check.name, nextRunTime.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(nextRunTime))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case asd.checksSemaphore <- struct{}{}:
+		}
+
+		asd.runCheck(check)
+		<-asd.checksSemaphore
+
+		nextRunTime = check.getNextRunTime(time.Now())
+	}
+}
+
+func (asd *apiStatusDaemon) runCheck(check *apiCheck) {
+	debugLogger.Printf("Running check %s...\n", check.name)
+
+	// Running checks are not canceled on shutdown, they are bounded by their timeout
+	run := check.run(asd.apiStatus.ctx)
+
+	checkResults := []*checkResult{run.result}
+	gaugeObservers := run.gaugeObservers
+	if asd.apiStatus.stateStore != nil {
+		stateGaugeObservers, transitions := asd.apiStatus.updateChecksStates([]*apiCheck{check}, checkResults, []*checkRun{run})
+		gaugeObservers = append(gaugeObservers, stateGaugeObservers...)
+		asd.apiStatus.notifyStatusChanges([]*apiCheck{check}, checkResults, transitions)
+	}
+
+	if run.err == nil {
+		asd.setCheckGaugeObservers(check.name, gaugeObservers)
+	}
+
+	logCheckResult(check, checkResults[0].Status, run.duration, run.err)
+
+	if asd.apiStatus.logShipper != nil {
+		if err := asd.apiStatus.logShipper.shipCheckLogs(asd.apiStatus.ctx, []*apiCheck{check}, checkResults, []*checkRun{run}); err != nil {
+			errorLogger.Printf("Error shipping check %s logs: %v\n", check.name, err)
+		}
+	}
+}
+
+// Replaces the check's last results, and registers a metric for each metric name that was not observed before
+func (asd *apiStatusDaemon) setCheckGaugeObservers(checkName string, gaugeObservers []metricRegister) {
+	newMetricRegisters := make([]metricRegister, 0)
+
+	asd.lock.Lock()
+	asd.checksGaugeObservers[checkName] = gaugeObservers
+
+	for _, metricReg := range gaugeObservers {
+		switch gaugeObserver := metricReg.(type) {
+		case *int64GaugeObserver:
+			if !asd.registeredMetrics[gaugeObserver.name] {
+				asd.registeredMetrics[gaugeObserver.name] = true
+				newMetricRegisters = append(newMetricRegisters, gaugeObserver)
+			}
+		case *float64GaugeObserver:
+			if !asd.registeredMetrics[gaugeObserver.name] {
+				asd.registeredMetrics[gaugeObserver.name] = true
+				newMetricRegisters = append(newMetricRegisters, gaugeObserver)
+			}
+		}
+	}
+
+	asd.lock.Unlock()
+
+	// Registering outside the lock, since collection holds the meter's lock while calling the callbacks
+	for _, metricReg := range newMetricRegisters {
+		asd.registerMetric(metricReg)
+	}
+}
+
+// Registers the gauge observer's metric with a callback that observes the last results of all checks
+func (asd *apiStatusDaemon) registerMetric(metricReg metricRegister) {
+	switch gaugeObserver := metricReg.(type) {
+	case *int64GaugeObserver:
+		_ = metric.Must(asd.meter).NewInt64GaugeObserver(gaugeObserver.name, asd.getInt64ObserverCallback(gaugeObserver.name), metric.WithDescription(gaugeObserver.description))
+	case *float64GaugeObserver:
+		_ = metric.Must(asd.meter).NewFloat64GaugeObserver(gaugeObserver.name, asd.getFloat64ObserverCallback(gaugeObserver.name), metric.WithDescription(gaugeObserver.description))
+	}
+}
+
+// Returns the last results of all checks, in the checks' order
+func (asd *apiStatusDaemon) getGaugeObservers() []metricRegister {
+	asd.lock.Lock()
+	defer asd.lock.Unlock()
+
+	gaugeObservers := make([]metricRegister, 0)
+
+	for _, check := range asd.apiStatus.checks {
+		gaugeObservers = append(gaugeObservers, asd.checksGaugeObservers[check.name]...)
+	}
+
+	return gaugeObservers
+}
+
+// Returns a callback that observes the last results of all checks for the metric
+func (asd *apiStatusDaemon) getInt64ObserverCallback(metricName string) func(context.Context, metric.Int64ObserverResult) {
+	return func(_ context.Context, result metric.Int64ObserverResult) {
+		asd.lock.Lock()
+		defer asd.lock.Unlock()
+
+		for _, check := range asd.apiStatus.checks {
+			for _, metricReg := range asd.checksGaugeObservers[check.name] {
+				if gaugeObserver, ok := metricReg.(*int64GaugeObserver); ok && gaugeObserver.name == metricName {
+					gaugeObserver.observe(result)
+				}
+			}
+		}
+	}
+}
+
+// Returns a callback that observes the last results of all checks for the metric
+func (asd *apiStatusDaemon) getFloat64ObserverCallback(metricName string) func(context.Context, metric.Float64ObserverResult) {
+	return func(_ context.Context, result metric.Float64ObserverResult) {
+		asd.lock.Lock()
+		defer asd.lock.Unlock()
+
+		for _, check := range asd.apiStatus.checks {
+			for _, metricReg := range asd.checksGaugeObservers[check.name] {
+				if gaugeObserver, ok := metricReg.(*float64GaugeObserver); ok && gaugeObserver.name == metricName {
+					gaugeObserver.observe(result)
+				}
+			}
+		}
+	}
+}
+
+func runDaemon(ctx context.Context) error {
+	setRegionLocation()
+	apiStatus, err := newLogzioApiStatus(context.Background(), daemonMode)
+	if err != nil {
+		return fmt.Errorf("error creating logzioApiStatus instance: %v", err)
+	}
+
+	daemon, err := newApiStatusDaemon(apiStatus, os.Getenv(metricsListenAddressEnvName))
+	if err != nil {
+		return err
+	}
+
+	infoLogger.Printf("Running %d checks in daemon mode...\n", len(apiStatus.checks))
+
+	return daemon.run(ctx)
+}
+
+// Runs the daemon until SIGTERM or SIGINT
+func runDaemonUntilSignal() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	return runDaemon(ctx)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDaemon(t *testing.T) {
+	err := os.Setenv(checksEnvName, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+    interval: 100ms
+  - name: orders
+    url: https://example.api:1234/orders
+    interval: 1h
+`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var usersCallsLock sync.Mutex
+	usersCalls := 0
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		func(request *http.Request) (*http.Response, error) {
+			usersCallsLock.Lock()
+			defer usersCallsLock.Unlock()
+
+			// The last results are exported, so the first call's status must not be exported
+			usersCalls++
+			if usersCalls == 1 {
+				return httpmock.NewStringResponse(http.StatusInternalServerError, "error"), nil
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+
+	statuses := make(map[string]interface{})
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+
+			for _, metric := range metrics {
+				if metric["__name__"] == statusMetricName {
+					statuses[metric[checkNameLabelName].(string)] = metric[statusMetricStatusLabelName]
+				}
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+
+	err = runDaemon(ctx)
+	require.NoError(t, err)
+
+	callCounts := httpmock.GetCallCountInfo()
+	assert.GreaterOrEqual(t, callCounts["GET https://example.api:1234/users"], 3)
+	assert.Equal(t, 1, callCounts["GET https://example.api:1234/orders"])
+	assert.Equal(t, map[string]interface{}{
+		"users":  successStatusMetricStatusLabelValue,
+		"orders": successStatusMetricStatusLabelValue,
+	}, statuses)
+
+	os.Clearenv()
+}
+
+func TestGetNextRunTime(t *testing.T) {
+	lastRunTime := time.Date(2022, time.March, 15, 10, 7, 30, 0, time.UTC)
+
+	intervalCheck, err := newApiCheck(&checkConfig{Name: "interval", URL: "https://example.api:1234", Method: http.MethodGet, Timeout: "10s", ExpectedStatusCode: "200", Interval: "30s"})
+	require.NoError(t, err)
+	assert.Equal(t, lastRunTime.Add(30*time.Second), intervalCheck.getNextRunTime(lastRunTime))
+
+	cronCheck, err := newApiCheck(&checkConfig{Name: "cron", URL: "https://example.api:1234", Method: http.MethodGet, Timeout: "10s", ExpectedStatusCode: "200", Cron: "*/5 * * * *"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, time.March, 15, 10, 10, 0, 0, time.UTC), cronCheck.getNextRunTime(lastRunTime))
+
+	defaultCheck, err := newApiCheck(&checkConfig{Name: "default", URL: "https://example.api:1234", Method: http.MethodGet, Timeout: "10s", ExpectedStatusCode: "200"})
+	require.NoError(t, err)
+	assert.Equal(t, lastRunTime.Add(defaultCheckInterval), defaultCheck.getNextRunTime(lastRunTime))
+}
+
+func TestNewLogzioApiStatus_BadSchedule(t *testing.T) {
+	for _, schedule := range []string{
+		`"interval": "1m", "cron": "* * * * *"`,
+		`"interval": "often"`,
+		`"interval": "-1m"`,
+		`"cron": "* * *"`,
+		`"cron": "0 0 31 2 *"`,
+	} {
+		err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users", `+schedule+`}]}`)
+		require.NoError(t, err)
+
+		err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+		require.NoError(t, err)
+
+		err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+		require.NoError(t, err)
+
+		_, err = newLogzioApiStatus(context.Background(), daemonMode)
+		require.Error(t, err, schedule)
+
+		os.Clearenv()
+	}
+}
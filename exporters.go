@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	metricsExporter "github.com/logzio/go-metrics-sdk"
+	"github.com/prometheus/prometheus/prompb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/number"
+	"go.opentelemetry.io/otel/metric/sdkapi"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	metricsExportersEnvName                  = "METRICS_EXPORTERS"
+	prometheusRemoteWriteURLEnvName          = "PROMETHEUS_REMOTE_WRITE_URL"
+	prometheusRemoteWriteBearerTokenEnvName  = "PROMETHEUS_REMOTE_WRITE_BEARER_TOKEN"
+	prometheusRemoteWriteUsernameEnvName     = "PROMETHEUS_REMOTE_WRITE_USERNAME"
+	prometheusRemoteWritePasswordEnvName     = "PROMETHEUS_REMOTE_WRITE_PASSWORD"
+	otlpMetricsEndpointEnvName               = "OTLP_METRICS_ENDPOINT"
+	otlpMetricsHeadersEnvName                = "OTLP_METRICS_HEADERS"
+	logzioMetricsExporterName                = "logzio"
+	prometheusRemoteWriteMetricsExporterName = "prometheus"
+	otlpMetricsExporterName                  = "otlp"
+	stdoutMetricsExporterName                = "stdout"
+	defaultMetricsExporters                  = logzioMetricsExporterName
+	noMetricsExporters                       = "none"
+	metricsExporterRemoteTimeout             = 30 * time.Second
+	prometheusMetricNameLabelName            = "__name__"
+)
+
+type metricRecord struct {
+	Name        string            `json:"name"`
+	Value       float64           `json:"value"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	description string
+	numberKind  number.Kind
+	time        time.Time
+	// The labels with their types, for exporters with typed attributes
+	attributes []attribute.KeyValue
+}
+
+type namedMetricsExporter struct {
+	name     string
+	exporter export.Exporter
+}
+
+// Exports the metrics to all of its exporters, even if some of them fail
+type multiMetricsExporter struct {
+	exporters []*namedMetricsExporter
+}
+
+// Writes a JSON line per metric
+type stdoutMetricsExporter struct {
+	output io.Writer
+}
+
+// Sends the metrics with the Prometheus remote write protocol
+type prometheusRemoteWriteMetricsExporter struct {
+	url         string
+	bearerToken string
+	username    string
+	password    string
+	client      *http.Client
+}
+
+// Sends the metrics as gauges with the OTLP/HTTP protocol, in JSON encoding, with the attributes' types
+type otlpMetricsExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// Returns the metrics exporters listed in METRICS_EXPORTERS (default: logzio)
+func (las *logzioApiStatus) getMetricsExporters() ([]*namedMetricsExporter, error) {
+	metricsExportersString := os.Getenv(metricsExportersEnvName)
+	if metricsExportersString == "" {
+		metricsExportersString = defaultMetricsExporters
+	}
+
+	// For example: in daemon mode, when the metrics are only scraped
+	if metricsExportersString == noMetricsExporters {
+		return []*namedMetricsExporter{}, nil
+	}
+
+	exporters := make([]*namedMetricsExporter, 0)
+	exporterNames := make(map[string]bool)
+
+	for _, name := range strings.Split(metricsExportersString, ",") {
+		name = strings.TrimSpace(name)
+		if exporterNames[name] {
+			return nil, fmt.Errorf("metrics exporter %s is not unique", name)
+		}
+
+		exporterNames[name] = true
+
+		var exporter export.Exporter
+		var err error
+
+		switch name {
+		case logzioMetricsExporterName:
+			if las.logzioMetricsListener == "" {
+				return nil, fmt.Errorf("%s must not be empty", logzioMetricsListenerEnvName)
+			}
+
+			if las.logzioMetricsToken == "" {
+				return nil, fmt.Errorf("%s must not be empty", logzioMetricsTokenEnvName)
+			}
+
+			exporter, err = las.newLogzioMetricsExporter()
+		case prometheusRemoteWriteMetricsExporterName:
+			exporter, err = newPrometheusRemoteWriteMetricsExporter()
+		case otlpMetricsExporterName:
+			exporter, err = newOtlpMetricsExporter()
+		case stdoutMetricsExporterName:
+			exporter = &stdoutMetricsExporter{output: os.Stdout}
+		default:
+			return nil, fmt.Errorf("metrics exporter %s must be %s, %s, %s or %s", name, logzioMetricsExporterName, prometheusRemoteWriteMetricsExporterName, otlpMetricsExporterName, stdoutMetricsExporterName)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s metrics exporter: %v", name, err)
+		}
+
+		exporters = append(exporters, &namedMetricsExporter{name: name, exporter: exporter})
+	}
+
+	return exporters, nil
+}
+
+func (las *logzioApiStatus) newLogzioMetricsExporter() (export.Exporter, error) {
+	return metricsExporter.NewRawExporter(metricsExporter.Config{
+		LogzioMetricsListener: las.logzioMetricsListener,
+		LogzioMetricsToken:    las.logzioMetricsToken,
+		RemoteTimeout:         metricsExporterRemoteTimeout,
+	})
+}
+
+func newPrometheusRemoteWriteMetricsExporter() (*prometheusRemoteWriteMetricsExporter, error) {
+	remoteWriteURL := os.Getenv(prometheusRemoteWriteURLEnvName)
+	if remoteWriteURL == "" {
+		return nil, fmt.Errorf("%s must not be empty", prometheusRemoteWriteURLEnvName)
+	}
+
+	return &prometheusRemoteWriteMetricsExporter{
+		url:         remoteWriteURL,
+		bearerToken: os.Getenv(prometheusRemoteWriteBearerTokenEnvName),
+		username:    os.Getenv(prometheusRemoteWriteUsernameEnvName),
+		password:    os.Getenv(prometheusRemoteWritePasswordEnvName),
+		client:      &http.Client{Timeout: metricsExporterRemoteTimeout},
+	}, nil
+}
+
+func newOtlpMetricsExporter() (*otlpMetricsExporter, error) {
+	endpoint := os.Getenv(otlpMetricsEndpointEnvName)
+	if endpoint == "" {
+		return nil, fmt.Errorf("%s must not be empty", otlpMetricsEndpointEnvName)
+	}
+
+	headers, err := parseHeaders(os.Getenv(otlpMetricsHeadersEnvName))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", otlpMetricsHeadersEnvName, err)
+	}
+
+	return &otlpMetricsExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   &http.Client{Timeout: metricsExporterRemoteTimeout},
+	}, nil
+}
+
+func newMultiMetricsExporter(exporters []*namedMetricsExporter) *multiMetricsExporter {
+	return &multiMetricsExporter{exporters: exporters}
+}
+
+func (mme *multiMetricsExporter) Export(ctx context.Context, res *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	exportErrors := make([]string, 0)
+
+	for _, namedExporter := range mme.exporters {
+		debugLogger.Printf("Exporting metrics to %s...\n", namedExporter.name)
+
+		if err := namedExporter.exporter.Export(ctx, res, reader); err != nil {
+			errorLogger.Printf("Error exporting metrics to %s: %v\n", namedExporter.name, err)
+			exportErrors = append(exportErrors, fmt.Sprintf("%s: %v", namedExporter.name, err))
+		}
+	}
+
+	if len(exportErrors) > 0 {
+		return fmt.Errorf("error exporting metrics: %s", strings.Join(exportErrors, "; "))
+	}
+
+	return nil
+}
+
+// The checks' metrics are gauges, so the temporality has no effect on them
+func (mme *multiMetricsExporter) TemporalityFor(*sdkapi.Descriptor, aggregation.Kind) aggregation.Temporality {
+	return aggregation.CumulativeTemporality
+}
+
+func (sme *stdoutMetricsExporter) Export(_ context.Context, res *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	records, err := getMetricRecords(reader)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(sme.output)
+
+	for _, record := range records {
+		err = encoder.Encode(struct {
+			Time   time.Time         `json:"time"`
+			Name   string            `json:"name"`
+			Value  float64           `json:"value"`
+			Labels map[string]string `json:"labels"`
+		}{record.time, record.Name, record.Value, getResourceLabels(res, record.Labels)})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sme *stdoutMetricsExporter) TemporalityFor(*sdkapi.Descriptor, aggregation.Kind) aggregation.Temporality {
+	return aggregation.CumulativeTemporality
+}
+
+func (prwme *prometheusRemoteWriteMetricsExporter) Export(ctx context.Context, res *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	records, err := getMetricRecords(reader)
+	if err != nil {
+		return err
+	}
+
+	writeRequest := &prompb.WriteRequest{}
+
+	for _, record := range records {
+		labels := getResourceLabels(res, record.Labels)
+		labels[prometheusMetricNameLabelName] = record.Name
+
+		timeseries := prompb.TimeSeries{
+			Samples: []prompb.Sample{{Value: record.Value, Timestamp: record.time.UnixMilli()}},
+		}
+
+		for name, value := range labels {
+			timeseries.Labels = append(timeseries.Labels, prompb.Label{Name: name, Value: value})
+		}
+
+		// Remote write requires the labels sorted by name
+		sort.Slice(timeseries.Labels, func(i, j int) bool {
+			return timeseries.Labels[i].Name < timeseries.Labels[j].Name
+		})
+
+		writeRequest.Timeseries = append(writeRequest.Timeseries, timeseries)
+	}
+
+	message, err := writeRequest.Marshal()
+	if err != nil {
+		return fmt.Errorf("error marshaling write request: %v", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, prwme.url, bytes.NewReader(snappy.Encode(nil, message)))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	if prwme.bearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+prwme.bearerToken)
+	} else if prwme.username != "" || prwme.password != "" {
+		request.SetBasicAuth(prwme.username, prwme.password)
+	}
+
+	return sendMetricsRequest(prwme.client, request)
+}
+
+func (prwme *prometheusRemoteWriteMetricsExporter) TemporalityFor(*sdkapi.Descriptor, aggregation.Kind) aggregation.Temporality {
+	return aggregation.CumulativeTemporality
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope     `json:"scope"`
+	Metrics []*otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Gauge       otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+// 64 bit integers are strings in the JSON encoding of OTLP
+type otlpNumberDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     *float64       `json:"asDouble,omitempty"`
+	AsInt        string         `json:"asInt,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// One of the values is set, by the attribute's type
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    string   `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (ome *otlpMetricsExporter) Export(ctx context.Context, res *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	records, err := getMetricRecords(reader)
+	if err != nil {
+		return err
+	}
+
+	metrics := make([]*otlpMetric, 0)
+	metricsByName := make(map[string]*otlpMetric)
+
+	for _, record := range records {
+		gaugeMetric, ok := metricsByName[record.Name]
+		if !ok {
+			gaugeMetric = &otlpMetric{Name: record.Name, Description: record.description}
+			metricsByName[record.Name] = gaugeMetric
+			metrics = append(metrics, gaugeMetric)
+		}
+
+		dataPoint := otlpNumberDataPoint{
+			Attributes:   getOtlpAttributes(record.attributes),
+			TimeUnixNano: strconv.FormatInt(record.time.UnixNano(), 10),
+		}
+
+		if record.numberKind == number.Int64Kind {
+			dataPoint.AsInt = strconv.FormatInt(int64(record.Value), 10)
+		} else {
+			value := record.Value
+			dataPoint.AsDouble = &value
+		}
+
+		gaugeMetric.Gauge.DataPoints = append(gaugeMetric.Gauge.DataPoints, dataPoint)
+	}
+
+	body, err := json.Marshal(otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource:     otlpResource{Attributes: getOtlpAttributes(res.Attributes())},
+			ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: meterName}, Metrics: metrics}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling OTLP request: %v", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ome.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	for key, value := range ome.headers {
+		request.Header.Set(key, value)
+	}
+
+	return sendMetricsRequest(ome.client, request)
+}
+
+func (ome *otlpMetricsExporter) TemporalityFor(*sdkapi.Descriptor, aggregation.Kind) aggregation.Temporality {
+	return aggregation.CumulativeTemporality
+}
+
+func sendMetricsRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer closeResponseBody(response.Body)
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		responseBody, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response status %s: %s", response.Status, string(responseBody))
+	}
+
+	return nil
+}
+
+// Returns the metrics' gauge values, sorted by name and labels
+func getMetricRecords(reader export.InstrumentationLibraryReader) ([]*metricRecord, error) {
+	records := make([]*metricRecord, 0)
+
+	err := reader.ForEach(func(_ instrumentation.Library, recordReader export.Reader) error {
+		return recordReader.ForEach(aggregation.CumulativeTemporalitySelector(), func(record export.Record) error {
+			lastValue, ok := record.Aggregation().(aggregation.LastValue)
+			if !ok {
+				return nil
+			}
+
+			value, valueTime, err := lastValue.LastValue()
+			if err != nil {
+				return err
+			}
+
+			records = append(records, &metricRecord{
+				Name:        record.Descriptor().Name(),
+				Value:       value.CoerceToFloat64(record.Descriptor().NumberKind()),
+				Labels:      getLabels(record.Labels().Iter()),
+				attributes:  record.Labels().ToSlice(),
+				description: record.Descriptor().Description(),
+				numberKind:  record.Descriptor().NumberKind(),
+				time:        valueTime,
+			})
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading metrics: %v", err)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+
+		return formatLabels(records[i].Labels) < formatLabels(records[j].Labels)
+	})
+
+	return records, nil
+}
+
+func getLabels(iterator attribute.Iterator) map[string]string {
+	labels := make(map[string]string)
+
+	for iterator.Next() {
+		label := iterator.Attribute()
+		labels[string(label.Key)] = label.Value.Emit()
+	}
+
+	return labels
+}
+
+// Returns the resource's labels (region, function, geohash) together with the given labels
+func getResourceLabels(res *resource.Resource, labels map[string]string) map[string]string {
+	resourceLabels := getLabels(res.Iter())
+
+	for name, value := range labels {
+		resourceLabels[name] = value
+	}
+
+	return resourceLabels
+}
+
+func getOtlpAttributes(attributes []attribute.KeyValue) []otlpKeyValue {
+	keyValues := make([]otlpKeyValue, 0, len(attributes))
+
+	for _, keyValue := range attributes {
+		keyValues = append(keyValues, otlpKeyValue{Key: string(keyValue.Key), Value: getOtlpValue(keyValue.Value)})
+	}
+
+	sort.Slice(keyValues, func(i, j int) bool {
+		return keyValues[i].Key < keyValues[j].Key
+	})
+
+	return keyValues
+}
+
+// Returns the value in the field of its type, where 64 bit integers are strings in the JSON encoding of OTLP.
+// Other types (the slices, which the checks don't have) are sent as their string.
+func getOtlpValue(value attribute.Value) otlpAnyValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		boolValue := value.AsBool()
+		return otlpAnyValue{BoolValue: &boolValue}
+	case attribute.INT64:
+		return otlpAnyValue{IntValue: strconv.FormatInt(value.AsInt64(), 10)}
+	case attribute.FLOAT64:
+		doubleValue := value.AsFloat64()
+		return otlpAnyValue{DoubleValue: &doubleValue}
+	default:
+		stringValue := value.Emit()
+		return otlpAnyValue{StringValue: &stringValue}
+	}
+}
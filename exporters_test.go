@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/sdkapi"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type testMetricsExporter struct {
+	err     error
+	exports int
+}
+
+func (tme *testMetricsExporter) Export(context.Context, *resource.Resource, export.InstrumentationLibraryReader) error {
+	tme.exports++
+	return tme.err
+}
+
+func (tme *testMetricsExporter) TemporalityFor(*sdkapi.Descriptor, aggregation.Kind) aggregation.Temporality {
+	return aggregation.CumulativeTemporality
+}
+
+func setTestExportersEnv(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+	require.NoError(t, err)
+}
+
+func TestRun_MetricsExportersFanOut(t *testing.T) {
+	setTestExportersEnv(t)
+
+	err := os.Setenv(metricsExportersEnvName, "logzio, prometheus, otlp")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	err = os.Setenv(prometheusRemoteWriteURLEnvName, "https://prometheus.example:9090/api/v1/write")
+	require.NoError(t, err)
+
+	err = os.Setenv(prometheusRemoteWriteUsernameEnvName, "user")
+	require.NoError(t, err)
+
+	err = os.Setenv(prometheusRemoteWritePasswordEnvName, "pass")
+	require.NoError(t, err)
+
+	err = os.Setenv(otlpMetricsEndpointEnvName, "https://collector.example:4318/v1/metrics")
+	require.NoError(t, err)
+
+	err = os.Setenv(otlpMetricsHeadersEnvName, "X-Api-Key=secret")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			assert.Len(t, metrics, 3)
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	httpmock.RegisterResponder(http.MethodPost, "https://prometheus.example:9090/api/v1/write",
+		func(request *http.Request) (*http.Response, error) {
+			username, password, ok := request.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "user", username)
+			assert.Equal(t, "pass", password)
+			assert.Equal(t, "snappy", request.Header.Get("Content-Encoding"))
+
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			require.Len(t, metrics, 3)
+
+			for _, metric := range metrics {
+				assert.Equal(t, "users", metric[checkNameLabelName])
+				assert.Equal(t, "us-east-1", metric[awsRegionLabelName])
+
+				if metric["__name__"] == statusMetricName {
+					assert.Equal(t, successStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
+					assert.Equal(t, float64(statusMetricValue), metric["value"])
+				}
+			}
+
+			// Prometheus accepts remote writes with 204
+			return httpmock.NewStringResponse(http.StatusNoContent, ""), nil
+		})
+
+	httpmock.RegisterResponder(http.MethodPost, "https://collector.example:4318/v1/metrics",
+		func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+			assert.Equal(t, "secret", request.Header.Get("X-Api-Key"))
+
+			otlpRequest := &otlpMetricsRequest{}
+			err := json.NewDecoder(request.Body).Decode(otlpRequest)
+			require.NoError(t, err)
+			require.Len(t, otlpRequest.ResourceMetrics, 1)
+
+			resourceMetrics := otlpRequest.ResourceMetrics[0]
+			assert.Contains(t, resourceMetrics.Resource.Attributes, otlpKeyValue{Key: awsRegionLabelName, Value: getOtlpValue(attribute.StringValue("us-east-1"))})
+			require.Len(t, resourceMetrics.ScopeMetrics, 1)
+
+			metricNames := make([]string, 0)
+
+			for _, metric := range resourceMetrics.ScopeMetrics[0].Metrics {
+				metricNames = append(metricNames, metric.Name)
+				require.Len(t, metric.Gauge.DataPoints, 1)
+
+				dataPoint := metric.Gauge.DataPoints[0]
+				assert.Contains(t, dataPoint.Attributes, otlpKeyValue{Key: checkNameLabelName, Value: getOtlpValue(attribute.StringValue("users"))})
+				assert.NotEmpty(t, dataPoint.TimeUnixNano)
+
+				switch metric.Name {
+				case statusMetricName:
+					assert.Equal(t, "1", dataPoint.AsInt)
+					assert.Nil(t, dataPoint.AsDouble)
+					assert.Contains(t, dataPoint.Attributes, otlpKeyValue{Key: statusMetricResponseStatusCodeLabelName, Value: otlpAnyValue{IntValue: "200"}})
+				case responseTimeMetricName:
+					assert.Empty(t, dataPoint.AsInt)
+					assert.NotNil(t, dataPoint.AsDouble)
+				}
+			}
+
+			assert.Equal(t, []string{responseBodyLengthMetricName, responseTimeMetricName, statusMetricName}, metricNames)
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	callCounts := httpmock.GetCallCountInfo()
+	assert.Equal(t, 1, callCounts["POST https://listener.logz.io:8053"])
+	assert.Equal(t, 1, callCounts["POST https://prometheus.example:9090/api/v1/write"])
+	assert.Equal(t, 1, callCounts["POST https://collector.example:4318/v1/metrics"])
+
+	os.Clearenv()
+}
+
+func TestCollectMetrics_StdoutMetricsExporter(t *testing.T) {
+	setTestExportersEnv(t)
+
+	err := os.Setenv(metricsExportersEnvName, stdoutMetricsExporterName)
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	// Logz.io listener and token are not needed without the logzio exporter
+	apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+	require.Len(t, apiStatus.metricsExporters, 1)
+
+	var output bytes.Buffer
+	apiStatus.metricsExporters[0].exporter = &stdoutMetricsExporter{output: &output}
+
+	gaugeObservers, _, err := apiStatus.runChecks()
+	require.NoError(t, err)
+
+	err = apiStatus.collectMetrics(gaugeObservers)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 3)
+
+	metricNames := make([]string, 0)
+
+	for _, line := range lines {
+		record := &struct {
+			Name   string            `json:"name"`
+			Value  float64           `json:"value"`
+			Labels map[string]string `json:"labels"`
+		}{}
+
+		err = json.Unmarshal([]byte(line), record)
+		require.NoError(t, err)
+
+		metricNames = append(metricNames, record.Name)
+		assert.Equal(t, "users", record.Labels[checkNameLabelName])
+		assert.Equal(t, "test", record.Labels[awsLambdaFunctionLabelName])
+
+		if record.Name == responseBodyLengthMetricName {
+			assert.Equal(t, float64(len("success")), record.Value)
+		}
+	}
+
+	assert.True(t, sort.StringsAreSorted(metricNames))
+
+	os.Clearenv()
+}
+
+func TestMultiMetricsExporter_ExportsToAll(t *testing.T) {
+	failingExporter := &testMetricsExporter{err: errors.New("unavailable")}
+	exporter := &testMetricsExporter{}
+
+	multiExporter := newMultiMetricsExporter([]*namedMetricsExporter{
+		{name: prometheusRemoteWriteMetricsExporterName, exporter: failingExporter},
+		{name: stdoutMetricsExporterName, exporter: exporter},
+	})
+
+	err := multiExporter.Export(context.Background(), resource.Empty(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prometheus: unavailable")
+	assert.Equal(t, 1, failingExporter.exports)
+	assert.Equal(t, 1, exporter.exports)
+}
+
+func TestSendMetricsRequest_BadStatus(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodPost, "https://collector.example:4318/v1/metrics",
+		httpmock.NewStringResponder(http.StatusBadRequest, "bad metrics"))
+
+	request, err := http.NewRequest(http.MethodPost, "https://collector.example:4318/v1/metrics", io.NopCloser(strings.NewReader("{}")))
+	require.NoError(t, err)
+
+	err = sendMetricsRequest(http.DefaultClient, request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad metrics")
+}
+
+func TestNewLogzioApiStatus_BadMetricsExporters(t *testing.T) {
+	for _, exportersEnv := range []map[string]string{
+		{metricsExportersEnvName: "datadog"},
+		{metricsExportersEnvName: "stdout,stdout"},
+		{metricsExportersEnvName: "logzio"},
+		{metricsExportersEnvName: "prometheus"},
+		{metricsExportersEnvName: "otlp"},
+		{metricsExportersEnvName: "otlp", otlpMetricsEndpointEnvName: "https://collector.example:4318/v1/metrics", otlpMetricsHeadersEnvName: "X-Api-Key"},
+	} {
+		err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+		require.NoError(t, err)
+
+		for name, value := range exportersEnv {
+			err = os.Setenv(name, value)
+			require.NoError(t, err)
+		}
+
+		_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+		assert.Error(t, err, exportersEnv)
+
+		os.Clearenv()
+	}
+}
+
+func TestNewLogzioApiStatus_NoMetricsExporters(t *testing.T) {
+	setTestExportersEnv(t)
+
+	err := os.Setenv(metricsExportersEnvName, noMetricsExporters)
+	require.NoError(t, err)
+
+	// The Lambda function, and its custom resource, would drop all the metrics
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), metricsExportersEnvName)
+
+	err = run(context.Background())
+	require.Error(t, err)
+
+	// The daemon's metrics are scraped
+	apiStatus, err := newLogzioApiStatus(context.Background(), daemonMode)
+	require.NoError(t, err)
+	assert.Empty(t, apiStatus.metricsExporters)
+
+	err = os.Setenv(metricsExportersEnvName, "stdout, prometheus")
+	require.NoError(t, err)
+
+	err = os.Setenv(prometheusRemoteWriteURLEnvName, "https://prometheus.example:9090/api/v1/write")
+	require.NoError(t, err)
+
+	apiStatus, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+	require.Len(t, apiStatus.metricsExporters, 2)
+	assert.Equal(t, stdoutMetricsExporterName, apiStatus.metricsExporters[0].name)
+	assert.Equal(t, prometheusRemoteWriteMetricsExporterName, apiStatus.metricsExporters[1].name)
+
+	os.Clearenv()
+}
+
+func TestGetOtlpValue(t *testing.T) {
+	for expectedJSON, value := range map[string]attribute.Value{
+		`{"stringValue":"users"}`: attribute.StringValue("users"),
+		`{"stringValue":""}`:      attribute.StringValue(""),
+		`{"boolValue":false}`:     attribute.BoolValue(false),
+		`{"intValue":"200"}`:      attribute.IntValue(200),
+		`{"intValue":"0"}`:        attribute.Int64Value(0),
+		`{"doubleValue":0.5}`:     attribute.Float64Value(0.5),
+		`{"stringValue":"[a b]"}`: attribute.StringSliceValue([]string{"a", "b"}),
+	} {
+		valueJSON, err := json.Marshal(getOtlpValue(value))
+		require.NoError(t, err)
+		assert.Equal(t, expectedJSON, string(valueJSON))
+	}
+}
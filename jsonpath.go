@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonPathRoot           = "$"
+	jsonPathLengthFunction = "length()"
+)
+
+var jsonPathOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// The characters of the operators, which outside quotes and brackets must start an operator
+const jsonPathOperatorCharacters = "=!<>"
+
+// A JSONPath expression, optionally compared to a JSON value (for example: $.items.length() > 0).
+// Without a comparison, the expression passes if the path exists.
+type jsonPathExpression struct {
+	expression string
+	path       []jsonPathSegment
+	operator   string
+	value      interface{}
+}
+
+type jsonPathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	isLength bool
+}
+
+func newJSONPathExpression(expression string) (*jsonPathExpression, error) {
+	pathString, operator, valueString, err := splitJSONPathExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := parseJSONPath(pathString)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonPathExp := &jsonPathExpression{
+		expression: expression,
+		path:       path,
+		operator:   operator,
+	}
+
+	if operator != "" {
+		if err = json.Unmarshal([]byte(valueString), &jsonPathExp.value); err != nil {
+			return nil, fmt.Errorf("value %s must be a JSON value: %v", valueString, err)
+		}
+	}
+
+	return jsonPathExp, nil
+}
+
+// Splits the expression into path, operator and value, ignoring operators inside quotes and brackets.
+// Fails on an operator character that does not start a supported operator (for example: a single =).
+func splitJSONPathExpression(expression string) (string, string, string, error) {
+	var quote rune
+	brackets := 0
+
+	for index, char := range expression {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '[':
+			brackets++
+		case char == ']':
+			brackets--
+		case brackets == 0 && strings.ContainsRune(jsonPathOperatorCharacters, char):
+			for _, operator := range jsonPathOperators {
+				if strings.HasPrefix(expression[index:], operator) {
+					return strings.TrimSpace(expression[:index]), operator, strings.TrimSpace(expression[index+len(operator):]), nil
+				}
+			}
+
+			return "", "", "", fmt.Errorf("JSONPath expression %s: operator must be one of %s", expression, strings.Join(jsonPathOperators, ", "))
+		}
+	}
+
+	return strings.TrimSpace(expression), "", "", nil
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, jsonPathRoot) {
+		return nil, fmt.Errorf("JSONPath %s must start with %s", path, jsonPathRoot)
+	}
+
+	segments := make([]jsonPathSegment, 0)
+	rest := path[len(jsonPathRoot):]
+
+	for rest != "" {
+		if len(segments) > 0 && segments[len(segments)-1].isLength {
+			return nil, fmt.Errorf("JSONPath %s: %s must be the last segment", path, jsonPathLengthFunction)
+		}
+
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+
+			key := rest[:end]
+			if key == "" {
+				return nil, fmt.Errorf("JSONPath %s: empty key", path)
+			}
+
+			if key == jsonPathLengthFunction {
+				segments = append(segments, jsonPathSegment{isLength: true})
+			} else {
+				segments = append(segments, jsonPathSegment{key: key})
+			}
+
+			rest = rest[end:]
+		case '[':
+			selector := strings.TrimLeft(rest[1:], " ")
+
+			// A quoted key ends at its closing quote, so it can have any other character (for example: ])
+			if selector != "" && (selector[0] == '\'' || selector[0] == '"') {
+				keyEnd := strings.IndexByte(selector[1:], selector[0])
+				if keyEnd == -1 {
+					return nil, fmt.Errorf("JSONPath %s: unclosed quote", path)
+				}
+
+				key := selector[1 : keyEnd+1]
+				afterKey := strings.TrimLeft(selector[keyEnd+2:], " ")
+				if !strings.HasPrefix(afterKey, "]") {
+					return nil, fmt.Errorf("JSONPath %s: unclosed bracket", path)
+				}
+
+				segments = append(segments, jsonPathSegment{key: key})
+				rest = afterKey[1:]
+				continue
+			}
+
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("JSONPath %s: unclosed bracket", path)
+			}
+
+			selector = strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			index, err := strconv.Atoi(selector)
+			if err != nil {
+				return nil, fmt.Errorf("JSONPath %s: selector %s must be a quoted key or an index", path, selector)
+			}
+
+			segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+		default:
+			return nil, fmt.Errorf("JSONPath %s: unexpected character %q", path, rest[0])
+		}
+	}
+
+	return segments, nil
+}
+
+// Evaluates the expression against the JSON document
+func (jpe *jsonPathExpression) evaluate(documentBytes []byte) (bool, error) {
+	var document interface{}
+
+	if err := json.Unmarshal(documentBytes, &document); err != nil {
+		return false, fmt.Errorf("response body is not valid JSON: %v", err)
+	}
+
+	value, found := jpe.lookup(document)
+	if jpe.operator == "" {
+		return found, nil
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	switch jpe.operator {
+	case "==":
+		return reflect.DeepEqual(value, jpe.value), nil
+	case "!=":
+		return !reflect.DeepEqual(value, jpe.value), nil
+	}
+
+	number, isNumber := value.(float64)
+	expectedNumber, isExpectedNumber := jpe.value.(float64)
+	if !isNumber || !isExpectedNumber {
+		return false, fmt.Errorf("operator %s can only compare numbers", jpe.operator)
+	}
+
+	switch jpe.operator {
+	case ">":
+		return number > expectedNumber, nil
+	case ">=":
+		return number >= expectedNumber, nil
+	case "<":
+		return number < expectedNumber, nil
+	default:
+		return number <= expectedNumber, nil
+	}
+}
+
+func (jpe *jsonPathExpression) lookup(document interface{}) (interface{}, bool) {
+	value := document
+
+	for _, segment := range jpe.path {
+		switch {
+		case segment.isLength:
+			switch typedValue := value.(type) {
+			case []interface{}:
+				value = float64(len(typedValue))
+			case map[string]interface{}:
+				value = float64(len(typedValue))
+			case string:
+				value = float64(len([]rune(typedValue)))
+			default:
+				return nil, false
+			}
+		case segment.isIndex:
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			index := segment.index
+			if index < 0 {
+				index += len(array)
+			}
+
+			if index < 0 || index >= len(array) {
+				return nil, false
+			}
+
+			value = array[index]
+		default:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			if value, ok = object[segment.key]; !ok {
+				return nil, false
+			}
+		}
+	}
+
+	return value, true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJSONPathDocument = `{"status": "ok", "count": 3, "items": [{"id": 1}, {"id": 2}], "meta": {"dotted.key": true, "a]b": 1, "x'y": "z"}, "empty": null}`
+
+func TestJSONPathExpression_Evaluate(t *testing.T) {
+	expressions := map[string]bool{
+		`$.status == "ok"`:                  true,
+		`$.status != "ok"`:                  false,
+		`$.count > 2`:                       true,
+		`$.count >= 3`:                      true,
+		`$.count < 3`:                       false,
+		`$.count <= 2`:                      false,
+		`$.items.length() > 0`:              true,
+		`$.items.length() == 2`:             true,
+		`$.items[0].id == 1`:                true,
+		`$.items[-1].id == 2`:               true,
+		`$.items[5].id == 1`:                false,
+		`$['meta']["dotted.key"]`:           true,
+		`$.meta['dotted.key'] == true`:      true,
+		`$.meta['a]b'] == 1`:                true,
+		`$.meta[ "a]b" ] == 1`:              true,
+		`$.meta["x'y"] == "z"`:              true,
+		`$.status == "a = b"`:               false,
+		`$.meta['a=b'] == 1`:                false,
+		`$.empty == null`:                   true,
+		`$.missing`:                         false,
+		`$.status.length() == 2`:            true,
+		`$ != null`:                         true,
+		`$.items == [{"id": 1}, {"id": 2}]`: true,
+	}
+
+	for expression, expected := range expressions {
+		jsonPathExp, err := newJSONPathExpression(expression)
+		require.NoError(t, err, expression)
+
+		matched, err := jsonPathExp.evaluate([]byte(testJSONPathDocument))
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, matched, expression)
+	}
+}
+
+func TestJSONPathExpression_BadExpression(t *testing.T) {
+	for _, expression := range []string{
+		"status", "$.", "$[abc]", "$.items[0", "$.items.length().id", `$.status == ok`, `$.status == 'ok'`,
+		// Quoted keys must be closed, and followed by the bracket
+		`$.meta['a]b`, `$.meta['a]b'`, `$.meta['a]b' x]`,
+		// Operators other than ==, !=, >, >=, <, <=
+		`$.status = "ok"`, `$.count =< 3`, `$.count => 3`, `$.count ! 3`, `$.count <> 3`, `$.count === 3`,
+	} {
+		_, err := newJSONPathExpression(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestJSONPathExpression_NotJSON(t *testing.T) {
+	jsonPathExp, err := newJSONPathExpression(`$.status == "ok"`)
+	require.NoError(t, err)
+
+	_, err = jsonPathExp.evaluate([]byte("ok"))
+	require.Error(t, err)
+}
+
+func TestJSONPathExpression_CompareNotNumbers(t *testing.T) {
+	jsonPathExp, err := newJSONPathExpression(`$.status > 1`)
+	require.NoError(t, err)
+
+	_, err = jsonPathExp.evaluate([]byte(testJSONPathDocument))
+	require.Error(t, err)
+}
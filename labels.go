@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	fullLabelValueMode                 = "full"
+	truncateLabelValueMode             = "truncate"
+	hashLabelValueMode                 = "hash"
+	omitLabelValueMode                 = "omit"
+	defaultLabelValueMode              = truncateLabelValueMode
+	defaultLabelValueMaxLength         = 256
+	truncatedLabelValueSuffix          = "..."
+	hashedLabelValuePrefix             = "sha256:"
+	errorTypeLabelName                 = "error_type"
+	dnsErrorTypeLabelValue             = "dns"
+	tlsErrorTypeLabelValue             = "tls"
+	refusedErrorTypeLabelValue         = "refused"
+	resetErrorTypeLabelValue           = "reset"
+	timeoutErrorTypeLabelValue         = "timeout"
+	otherErrorTypeLabelValue           = "other"
+	connectTimeoutPhaseLabelValue      = "connect"
+	tlsHandshakeTimeoutPhaseLabelValue = "tls_handshake"
+	responseTimeoutPhaseLabelValue     = "response"
+	responseBodyLabelModeEnvName       = "RESPONSE_BODY_LABEL_MODE"
+	errorLabelModeEnvName              = "ERROR_LABEL_MODE"
+	labelValueMaxLengthEnvName         = "LABEL_VALUE_MAX_LENGTH"
+)
+
+// How the response_body and error labels are set, since their values are unbounded and may contain sensitive data
+type labelValuesConfig struct {
+	ResponseBody string `yaml:"response_body"`
+	Error        string `yaml:"error"`
+	MaxLength    int    `yaml:"max_length"`
+}
+
+func validateLabelValueMode(mode string) error {
+	switch mode {
+	case fullLabelValueMode, truncateLabelValueMode, hashLabelValueMode, omitLabelValueMode:
+		return nil
+	default:
+		return fmt.Errorf("label value mode %s must be %s, %s, %s or %s", mode, fullLabelValueMode, truncateLabelValueMode, hashLabelValueMode, omitLabelValueMode)
+	}
+}
+
+// Returns the label with its value truncated or hashed, or no label if it is omitted
+func getModeLabel(name string, value string, mode string, maxLength int) []attribute.KeyValue {
+	switch mode {
+	case omitLabelValueMode:
+		return nil
+	case hashLabelValueMode:
+		hash := sha256.Sum256([]byte(value))
+		return []attribute.KeyValue{attribute.String(name, hashedLabelValuePrefix+hex.EncodeToString(hash[:]))}
+	case truncateLabelValueMode:
+		if runes := []rune(value); len(runes) > maxLength {
+			value = string(runes[:maxLength]) + truncatedLabelValueSuffix
+		}
+	}
+
+	return []attribute.KeyValue{attribute.String(name, value)}
+}
+
+func (ac *apiCheck) getResponseBodyLabel(responseBodyBytes []byte) []attribute.KeyValue {
+	return getModeLabel(statusMetricResponseBodyLabelName, secretRedactor.redactText(string(responseBodyBytes)), ac.responseBodyLabelMode, ac.labelValueMaxLength)
+}
+
+func (ac *apiCheck) getErrorLabel(err error) []attribute.KeyValue {
+	return getModeLabel(statusMetricErrorLabelName, secretRedactor.redactText(err.Error()), ac.errorLabelMode, ac.labelValueMaxLength)
+}
+
+// Returns the error label together with the error type label, for errors of requests and responses
+func (ac *apiCheck) getRequestErrorLabels(err error) []attribute.KeyValue {
+	return append(ac.getErrorLabel(err), attribute.String(errorTypeLabelName, getErrorType(err)))
+}
+
+// Classifies request and response errors, so failures can be grouped regardless of their messages
+func getErrorType(err error) string {
+	var dnsError *net.DNSError
+	var netError net.Error
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var certificateInvalidError x509.CertificateInvalidError
+	var recordHeaderError tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &dnsError):
+		return dnsErrorTypeLabelValue
+	case errors.As(err, &unknownAuthorityError), errors.As(err, &hostnameError), errors.As(err, &certificateInvalidError),
+		errors.As(err, &recordHeaderError), strings.Contains(err.Error(), "tls: "):
+		return tlsErrorTypeLabelValue
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return refusedErrorTypeLabelValue
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return resetErrorTypeLabelValue
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netError) && netError.Timeout():
+		return timeoutErrorTypeLabelValue
+	default:
+		return otherErrorTypeLabelValue
+	}
+}
+
+// Returns the phase of the request that timed out: connecting (including the DNS lookup), the TLS handshake, or
+// waiting for and reading the response
+func getTimeoutPhase(err error) string {
+	var opError *net.OpError
+
+	switch {
+	case errors.As(err, &opError) && opError.Op == "dial":
+		return connectTimeoutPhaseLabelValue
+	case strings.Contains(err.Error(), "TLS handshake timeout"):
+		return tlsHandshakeTimeoutPhaseLabelValue
+	default:
+		return responseTimeoutPhaseLabelValue
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestGetModeLabel(t *testing.T) {
+	value := strings.Repeat("é", 10)
+
+	assert.Equal(t, []attribute.KeyValue{attribute.String("label", value)}, getModeLabel("label", value, fullLabelValueMode, 4))
+	assert.Equal(t, []attribute.KeyValue{attribute.String("label", "éééé...")}, getModeLabel("label", value, truncateLabelValueMode, 4))
+	assert.Equal(t, []attribute.KeyValue{attribute.String("label", value)}, getModeLabel("label", value, truncateLabelValueMode, 10))
+	assert.Nil(t, getModeLabel("label", value, omitLabelValueMode, 4))
+
+	hashLabel := getModeLabel("label", value, hashLabelValueMode, 4)
+	require.Len(t, hashLabel, 1)
+	assert.True(t, strings.HasPrefix(hashLabel[0].Value.AsString(), hashedLabelValuePrefix))
+	assert.Len(t, hashLabel[0].Value.AsString(), len(hashedLabelValuePrefix)+64)
+	assert.Equal(t, hashLabel, getModeLabel("label", value, hashLabelValueMode, 4))
+}
+
+func TestGetErrorType(t *testing.T) {
+	for expectedErrorType, err := range map[string]error{
+		dnsErrorTypeLabelValue:     &url.Error{Op: "Get", URL: "https://example.api", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "example.api", IsNotFound: true}}},
+		tlsErrorTypeLabelValue:     &url.Error{Op: "Get", URL: "https://example.api", Err: x509.UnknownAuthorityError{}},
+		refusedErrorTypeLabelValue: &url.Error{Op: "Get", URL: "https://example.api", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}},
+		resetErrorTypeLabelValue:   &url.Error{Op: "Get", URL: "https://example.api", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}},
+		timeoutErrorTypeLabelValue: &url.Error{Op: "Get", URL: "https://example.api", Err: context.DeadlineExceeded},
+		otherErrorTypeLabelValue:   errors.New("unknown"),
+	} {
+		assert.Equal(t, expectedErrorType, getErrorType(err), err.Error())
+	}
+
+	assert.Equal(t, resetErrorTypeLabelValue, getErrorType(fmt.Errorf("error reading body: %w", io.ErrUnexpectedEOF)))
+}
+
+func TestGetTimeoutPhase(t *testing.T) {
+	for expectedPhase, err := range map[string]error{
+		connectTimeoutPhaseLabelValue:      &url.Error{Op: "Get", URL: "https://example.api", Err: &net.OpError{Op: "dial", Err: context.DeadlineExceeded}},
+		tlsHandshakeTimeoutPhaseLabelValue: &url.Error{Op: "Get", URL: "https://example.api", Err: errors.New("net/http: TLS handshake timeout")},
+		responseTimeoutPhaseLabelValue:     &url.Error{Op: "Get", URL: "https://example.api", Err: context.DeadlineExceeded},
+	} {
+		assert.Equal(t, expectedPhase, getTimeoutPhase(err), err.Error())
+	}
+}
+
+func TestGetErrorType_ConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	client := &http.Client{Transport: &http.Transport{}}
+	_, err = client.Get("http://" + address)
+	require.Error(t, err)
+	assert.Equal(t, refusedErrorTypeLabelValue, getErrorType(err))
+}
+
+func TestNewApiCheck_LabelValues(t *testing.T) {
+	check, err := newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: http.MethodGet, Timeout: "10s", ExpectedStatusCode: "200"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultLabelValueMode, check.responseBodyLabelMode)
+	assert.Equal(t, defaultLabelValueMode, check.errorLabelMode)
+	assert.Equal(t, defaultLabelValueMaxLength, check.labelValueMaxLength)
+
+	for _, labelValues := range []labelValuesConfig{
+		{ResponseBody: "partial"},
+		{Error: "redact"},
+		{MaxLength: -1},
+	} {
+		_, err = newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: http.MethodGet, Timeout: "10s", ExpectedStatusCode: "200", LabelValues: labelValues})
+		assert.Error(t, err, labelValues)
+	}
+}
+
+func TestRun_NoMatchResponseBodyLabelValues(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(checksEnvName, `
+checks:
+  - name: users-truncated
+    url: https://example.api:1234/users
+    expected_body: success
+    label_values:
+      max_length: 10
+  - name: users-hashed
+    url: https://example.api:1234/users
+    body_assertions:
+      - type: jsonpath
+        value: $.status
+    label_values:
+      response_body: hash
+      error: omit
+`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	responseBody := strings.Repeat("<html>", 1000)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, responseBody))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+
+			statuses := 0
+
+			for _, metric := range metrics {
+				if metric["__name__"] != statusMetricName {
+					continue
+				}
+
+				statuses++
+
+				switch metric[checkNameLabelName] {
+				case "users-truncated":
+					assert.Equal(t, "<html><htm...", metric[statusMetricResponseBodyLabelName])
+				case "users-hashed":
+					assert.Equal(t, getModeLabel("", responseBody, hashLabelValueMode, 0)[0].Value.AsString(), metric[statusMetricResponseBodyLabelName])
+					assert.NotContains(t, metric, statusMetricErrorLabelName)
+				}
+			}
+
+			assert.Equal(t, 2, statuses)
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	os.Clearenv()
+}
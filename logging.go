@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const (
+	logLevelEnvName       = "LOG_LEVEL"
+	durationLogKey        = "duration_ms"
+	errorLogKey           = "error"
+	checksLogKey          = "checks"
+	succeededChecksLogKey = "succeeded"
+	failedChecksLogKey    = "failed"
+	statusesLogKey        = "statuses"
+	resultsLogKey         = "results"
+)
+
+var (
+	logLevel  = new(slog.LevelVar)
+	logOutput = newRedactingWriter(os.Stdout)
+	logger    = slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: logLevel}))
+	// Unstructured log lines, logged as the message of JSON records in their level
+	debugLogger = slog.NewLogLogger(logger.Handler(), slog.LevelDebug)
+	infoLogger  = slog.NewLogLogger(logger.Handler(), slog.LevelInfo)
+	errorLogger = slog.NewLogLogger(logger.Handler(), slog.LevelError)
+)
+
+// The result of one check in the summary record of a run
+type checkSummary struct {
+	Name     string  `json:"check_name"`
+	URL      string  `json:"url"`
+	Method   string  `json:"method"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration_ms"`
+}
+
+// Sets the output of the logs, and their level from LOG_LEVEL (debug, info, warn or error, default: info)
+func setLoggers(output io.Writer) error {
+	level := slog.LevelInfo
+
+	if levelString := os.Getenv(logLevelEnvName); levelString != "" {
+		if err := level.UnmarshalText([]byte(levelString)); err != nil {
+			return fmt.Errorf("%s must be debug, info, warn or error", logLevelEnvName)
+		}
+	}
+
+	logLevel.Set(level)
+	logOutput.setOutput(output)
+
+	return nil
+}
+
+func getMilliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// Logs a record of the check's result, in level warn if the check did not succeed and error if it failed to run
+func logCheckResult(check *apiCheck, status string, duration time.Duration, err error) {
+	attributes := []any{
+		checkNameLabelName, check.name,
+		urlLabelName, check.getRedactedURL(),
+		methodLabelName, check.method,
+		statusMetricStatusLabelName, status,
+		durationLogKey, getMilliseconds(duration),
+	}
+
+	switch {
+	case err != nil:
+		logger.Error("Check failed to run", append(attributes, errorLogKey, err.Error())...)
+	case status != successStatusMetricStatusLabelValue:
+		logger.Warn("Check finished", attributes...)
+	default:
+		logger.Info("Check finished", attributes...)
+	}
+}
+
+// Logs a record for each check, and one summary record of the run with the results of all checks
+func logChecksResults(checks []*apiCheck, checkResults []*checkResult, checksRuns []*checkRun, runDuration time.Duration) {
+	summaries := make([]*checkSummary, 0, len(checkResults))
+	statuses := make(map[string]int)
+
+	for index, result := range checkResults {
+		logCheckResult(checks[index], result.Status, checksRuns[index].duration, checksRuns[index].err)
+
+		summaries = append(summaries, &checkSummary{
+			Name:     result.Name,
+			URL:      result.URL,
+			Method:   result.Method,
+			Status:   result.Status,
+			Duration: getMilliseconds(checksRuns[index].duration),
+		})
+		statuses[result.Status]++
+	}
+
+	logger.Info("Checks finished",
+		checksLogKey, len(checkResults),
+		succeededChecksLogKey, statuses[successStatusMetricStatusLabelValue],
+		failedChecksLogKey, len(checkResults)-statuses[successStatusMetricStatusLabelValue],
+		statusesLogKey, statuses,
+		durationLogKey, getMilliseconds(runDuration),
+		resultsLogKey, summaries)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	logzioLogsListenerEnvName      = "LOGZIO_LOGS_LISTENER"
+	logzioLogsTokenEnvName         = "LOGZIO_LOGS_TOKEN"
+	logzioLogsTypeEnvName          = "LOGZIO_LOGS_TYPE"
+	logzioLogsMaxBodyLengthEnvName = "LOGZIO_LOGS_MAX_BODY_LENGTH"
+	defaultLogzioLogsType          = "api-status"
+	defaultLogzioLogsMaxBodyLength = 32 * 1024
+	// Logz.io drops logs larger than 500KB, and bulks larger than 10MB
+	maxLogDocumentSize  = 500 * 1000
+	maxLogsBulkSize     = 10 * 1000 * 1000
+	logsShipperAttempts = 3
+	logsShipperBackoff  = time.Second
+	logsShipperTimeout  = 30 * time.Second
+)
+
+// Ships a JSON document with the full result of each check to the Logz.io logs listener
+type logzioLogShipper struct {
+	client        *http.Client
+	url           string
+	maxBodyLength int
+	attempts      int
+	backoff       time.Duration
+}
+
+// The log document of one check run
+type checkLogDocument struct {
+	Timestamp         string             `json:"@timestamp"`
+	Message           string             `json:"message"`
+	CheckName         string             `json:"check_name"`
+	URL               string             `json:"url"`
+	Method            string             `json:"method"`
+	Status            string             `json:"status"`
+	Duration          float64            `json:"duration_ms"`
+	Labels            map[string]string  `json:"labels,omitempty"`
+	Metrics           map[string]float64 `json:"metrics,omitempty"`
+	Request           checkLogRequest    `json:"request"`
+	Response          *checkLogResponse  `json:"response,omitempty"`
+	Error             string             `json:"error,omitempty"`
+	AwsRegion         string             `json:"aws_region,omitempty"`
+	AwsLambdaFunction string             `json:"aws_lambda_function,omitempty"`
+}
+
+type checkLogRequest struct {
+	Headers    map[string]string `json:"headers,omitempty"`
+	BodyLength int               `json:"body_length"`
+}
+
+type checkLogResponse struct {
+	StatusCode    int               `json:"status_code"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	BodyLength    int               `json:"body_length"`
+	BodyTruncated bool              `json:"body_truncated,omitempty"`
+}
+
+// Returns nil if the logs listener is not set, since shipping the checks logs is optional
+func getLogzioLogShipper() (*logzioLogShipper, error) {
+	listener := os.Getenv(logzioLogsListenerEnvName)
+	if listener == "" {
+		return nil, nil
+	}
+
+	token := os.Getenv(logzioLogsTokenEnvName)
+	if token == "" {
+		return nil, fmt.Errorf("%s must not be empty", logzioLogsTokenEnvName)
+	}
+
+	logType := os.Getenv(logzioLogsTypeEnvName)
+	if logType == "" {
+		logType = defaultLogzioLogsType
+	}
+
+	maxBodyLength := defaultLogzioLogsMaxBodyLength
+
+	if maxBodyLengthString := os.Getenv(logzioLogsMaxBodyLengthEnvName); maxBodyLengthString != "" {
+		var err error
+		if maxBodyLength, err = strconv.Atoi(maxBodyLengthString); err != nil || maxBodyLength < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number", logzioLogsMaxBodyLengthEnvName)
+		}
+	}
+
+	return newLogzioLogShipper(listener, token, logType, maxBodyLength)
+}
+
+func newLogzioLogShipper(listener string, token string, logType string, maxBodyLength int) (*logzioLogShipper, error) {
+	listenerURL, err := url.Parse(listener)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing logs listener %s: %v", listener, err)
+	}
+
+	query := listenerURL.Query()
+	query.Set("token", token)
+	query.Set("type", logType)
+	listenerURL.RawQuery = query.Encode()
+
+	return &logzioLogShipper{
+		client:        &http.Client{Timeout: logsShipperTimeout},
+		url:           listenerURL.String(),
+		maxBodyLength: maxBodyLength,
+		attempts:      logsShipperAttempts,
+		backoff:       logsShipperBackoff,
+	}, nil
+}
+
+// Ships a log document for each check, in bulks within the listener's size limit
+func (lls *logzioLogShipper) shipCheckLogs(ctx context.Context, checks []*apiCheck, checkResults []*checkResult, checksRuns []*checkRun) error {
+	var bulk bytes.Buffer
+
+	for index, result := range checkResults {
+		documentBytes, err := lls.marshalCheckLogDocument(lls.newCheckLogDocument(checks[index], result, checksRuns[index]))
+		if err != nil {
+			errorLogger.Printf("Error creating check %s log: %v\n", result.Name, err)
+			continue
+		}
+
+		if bulk.Len() > 0 && bulk.Len()+len(documentBytes)+1 > maxLogsBulkSize {
+			if err = lls.sendBulk(ctx, bulk.Bytes()); err != nil {
+				return err
+			}
+
+			bulk.Reset()
+		}
+
+		bulk.Write(documentBytes)
+		bulk.WriteByte('\n')
+	}
+
+	if bulk.Len() == 0 {
+		return nil
+	}
+
+	return lls.sendBulk(ctx, bulk.Bytes())
+}
+
+func (lls *logzioLogShipper) newCheckLogDocument(check *apiCheck, result *checkResult, run *checkRun) *checkLogDocument {
+	document := &checkLogDocument{
+		Timestamp:         run.start.UTC().Format(time.RFC3339Nano),
+		Message:           fmt.Sprintf("Check %s finished with status %s", result.Name, result.Status),
+		CheckName:         result.Name,
+		URL:               result.URL,
+		Method:            result.Method,
+		Status:            result.Status,
+		Duration:          getMilliseconds(run.duration),
+		Labels:            result.Labels,
+		Metrics:           result.metrics,
+		Request:           checkLogRequest{Headers: getRedactedHeaders(check.headers), BodyLength: len(check.body)},
+		AwsRegion:         awsRegion,
+		AwsLambdaFunction: os.Getenv(awsLambdaFunctionNameEnvName),
+	}
+
+	if run.err != nil {
+		document.Error = secretRedactor.redactText(run.err.Error())
+	}
+
+	if run.response != nil {
+		responseHeaders := make(map[string]string, len(run.response.header))
+		for name, values := range run.response.header {
+			responseHeaders[name] = strings.Join(values, ", ")
+		}
+
+		body, truncated := truncateString(string(run.response.body), lls.maxBodyLength)
+		document.Response = &checkLogResponse{
+			StatusCode:    run.response.statusCode,
+			Headers:       getRedactedHeaders(responseHeaders),
+			Body:          secretRedactor.redactText(body),
+			BodyLength:    len(run.response.body),
+			BodyTruncated: truncated,
+		}
+	}
+
+	return document
+}
+
+// Drops the response body of documents over the size limit, since it is the only unbounded field
+func (lls *logzioLogShipper) marshalCheckLogDocument(document *checkLogDocument) ([]byte, error) {
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(documentBytes) > maxLogDocumentSize && document.Response != nil {
+		document.Response.Body = ""
+		document.Response.BodyTruncated = true
+
+		if documentBytes, err = json.Marshal(document); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(documentBytes) > maxLogDocumentSize {
+		return nil, fmt.Errorf("log size %d is larger than %d", len(documentBytes), maxLogDocumentSize)
+	}
+
+	return documentBytes, nil
+}
+
+// Sends the bulk, retrying with exponential backoff on connection errors, 429 and 5xx responses
+func (lls *logzioLogShipper) sendBulk(ctx context.Context, bulk []byte) error {
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, lls.url, bytes.NewReader(bulk))
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", "application/json")
+
+		return request, nil
+	}
+
+	return sendWithRetries(ctx, lls.client, newRequest, lls.attempts, lls.backoff, "logs")
+}
+
+// Sends a new request from newRequest until it succeeds, retrying with exponential backoff on connection errors, 429 and 5xx responses
+func sendWithRetries(ctx context.Context, client *http.Client, newRequest func(context.Context) (*http.Request, error), attempts int, backoff time.Duration, description string) error {
+	for attempt := 1; ; attempt++ {
+		retryable, err := sendOnce(ctx, client, newRequest)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable || attempt >= attempts {
+			return fmt.Errorf("error sending %s (attempt %d of %d): %v", description, attempt, attempts, err)
+		}
+
+		debugLogger.Printf("Error sending %s, retrying in %s: %v\n", description, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error sending %s: %v", description, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// Returns whether the request can be retried if it failed
+func sendOnce(ctx context.Context, client *http.Client, newRequest func(context.Context) (*http.Request, error)) (bool, error) {
+	request, err := newRequest(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return true, err
+	}
+
+	defer closeResponseBody(response.Body)
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, maxDiscardedRetryResponseBodySize))
+		retryable := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+
+		return retryable, fmt.Errorf("unexpected response status %s: %s", response.Status, string(responseBody))
+	}
+
+	return false, nil
+}
+
+func getRedactedHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	redactedHeaders := make(map[string]string, len(headers))
+	for name, value := range headers {
+		redactedHeaders[name] = secretRedactor.redactHeaderValue(name, value)
+	}
+
+	return redactedHeaders
+}
+
+// Truncates the string to at most maxLength bytes, without splitting a character
+func truncateString(value string, maxLength int) (string, bool) {
+	if len(value) <= maxLength {
+		return value, false
+	}
+
+	cut := maxLength
+	for cut > 0 && cut > maxLength-utf8.UTFMax && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+
+	return value[:cut], true
+}
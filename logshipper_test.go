@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_ShipsCheckLogs(t *testing.T) {
+	setTestExportersEnv(t)
+
+	err := os.Setenv(checksEnvName, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+    headers:
+      X-Api-Key: secret
+    expected_body: success
+  - name: orders
+    url: https://example.api:1234/orders
+`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioLogsListenerEnvName, "https://listener.logz.io:8071")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioLogsTokenEnvName, "123456789b")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioLogsMaxBodyLengthEnvName, "4")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		func(request *http.Request) (*http.Response, error) {
+			response := httpmock.NewStringResponse(http.StatusOK, "failure")
+			response.Header.Set("Set-Cookie", "session=secret")
+			response.Header.Set("Content-Type", "text/plain")
+
+			return response, nil
+		})
+
+	documents := make([]map[string]interface{}, 0)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8071",
+		func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, "123456789b", request.URL.Query().Get("token"))
+			assert.Equal(t, defaultLogzioLogsType, request.URL.Query().Get("type"))
+
+			scanner := bufio.NewScanner(request.Body)
+			for scanner.Scan() {
+				document := make(map[string]interface{})
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &document))
+				documents = append(documents, document)
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, documents, 2)
+
+	users := documents[0]
+	assert.Equal(t, "users", users["check_name"])
+	assert.Equal(t, noMatchResponseBodyStatusMetricStatusLabelValue, users["status"])
+	assert.Equal(t, "Check users finished with status no_match_response_body", users["message"])
+	assert.Equal(t, "us-east-1", users["aws_region"])
+	assert.NotEmpty(t, users["@timestamp"])
+	assert.Contains(t, users, "duration_ms")
+	assert.Equal(t, map[string]interface{}{"X-Api-Key": redactedValue}, users["request"].(map[string]interface{})["headers"])
+	assert.Equal(t, "success", users["labels"].(map[string]interface{})[statusMetricExpectedResponseBodyLabelName])
+	assert.Contains(t, users["metrics"], responseTimeMetricName)
+
+	response := users["response"].(map[string]interface{})
+	assert.Equal(t, float64(http.StatusOK), response["status_code"])
+	assert.Equal(t, "fail", response["body"])
+	assert.Equal(t, float64(len("failure")), response["body_length"])
+	assert.Equal(t, true, response["body_truncated"])
+	assert.Equal(t, map[string]interface{}{"Set-Cookie": redactedValue, "Content-Type": "text/plain"}, response["headers"])
+
+	orders := documents[1]
+	assert.Equal(t, "orders", orders["check_name"])
+	assert.Equal(t, connectionFailedStatusMetricStatusLabelValue, orders["status"])
+	assert.Contains(t, orders["labels"].(map[string]interface{})[statusMetricErrorLabelName], "no responder found")
+	assert.NotContains(t, orders, "response")
+
+	os.Clearenv()
+}
+
+func TestLogzioLogShipper_Retries(t *testing.T) {
+	shipper, err := newLogzioLogShipper("https://listener.logz.io:8071", "123456789b", defaultLogzioLogsType, defaultLogzioLogsMaxBodyLength)
+	require.NoError(t, err)
+	shipper.backoff = time.Millisecond
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	statusCodes := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8071",
+		func(request *http.Request) (*http.Response, error) {
+			statusCode := statusCodes[0]
+			statusCodes = statusCodes[1:]
+
+			return httpmock.NewStringResponse(statusCode, ""), nil
+		})
+
+	err = shipper.sendBulk(context.Background(), []byte("{}\n"))
+	require.NoError(t, err)
+	assert.Empty(t, statusCodes)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8071",
+		httpmock.NewStringResponder(http.StatusBadGateway, "unavailable"))
+	httpmock.ZeroCallCounters()
+
+	err = shipper.sendBulk(context.Background(), []byte("{}\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "attempt 3 of 3")
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8071",
+		httpmock.NewStringResponder(http.StatusUnauthorized, "bad token"))
+	httpmock.ZeroCallCounters()
+
+	err = shipper.sendBulk(context.Background(), []byte("{}\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad token")
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func TestMarshalCheckLogDocument_SizeLimit(t *testing.T) {
+	shipper, err := newLogzioLogShipper("https://listener.logz.io:8071", "123456789b", defaultLogzioLogsType, maxLogDocumentSize)
+	require.NoError(t, err)
+
+	document := &checkLogDocument{
+		CheckName: "users",
+		Response:  &checkLogResponse{StatusCode: http.StatusOK, Body: strings.Repeat("a", maxLogDocumentSize)},
+	}
+
+	documentBytes, err := shipper.marshalCheckLogDocument(document)
+	require.NoError(t, err)
+	assert.Less(t, len(documentBytes), maxLogDocumentSize)
+	assert.Empty(t, document.Response.Body)
+	assert.True(t, document.Response.BodyTruncated)
+}
+
+func TestTruncateString(t *testing.T) {
+	for _, test := range []struct {
+		value             string
+		maxLength         int
+		expectedValue     string
+		expectedTruncated bool
+	}{
+		{"success", 10, "success", false},
+		{"success", 7, "success", false},
+		{"success", 4, "succ", true},
+		{"héllo", 2, "h", true},
+		{"héllo", 3, "hé", true},
+		{"success", 0, "", true},
+	} {
+		value, truncated := truncateString(test.value, test.maxLength)
+		assert.Equal(t, test.expectedValue, value, test.value)
+		assert.Equal(t, test.expectedTruncated, truncated, test.value)
+	}
+}
+
+func TestNewLogzioApiStatus_BadLogShipper(t *testing.T) {
+	for _, logsEnv := range []map[string]string{
+		{logzioLogsListenerEnvName: "https://listener.logz.io:8071"},
+		{logzioLogsListenerEnvName: "https://listener.logz.io:8071", logzioLogsTokenEnvName: "123456789b", logzioLogsMaxBodyLengthEnvName: "-1"},
+	} {
+		err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+		require.NoError(t, err)
+
+		err = os.Setenv(metricsExportersEnvName, stdoutMetricsExporterName)
+		require.NoError(t, err)
+
+		for name, value := range logsEnv {
+			err = os.Setenv(name, value)
+			require.NoError(t, err)
+		}
+
+		_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+		assert.Error(t, err, logsEnv)
+
+		os.Clearenv()
+	}
+}
@@ -2,80 +2,120 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-lambda-go/cfn"
 	"github.com/aws/aws-lambda-go/lambda"
-	metricsExporter "github.com/logzio/go-metrics-sdk"
 	"github.com/mmcloughlin/geohash"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 )
 
 const (
-	apiUrlEnvName                                      = "API_URL"
-	methodEnvName                                      = "METHOD"
-	headersEnvName                                     = "HEADERS"
-	bodyEnvName                                        = "BODY"
-	bearerTokenEnvName                                 = "BEARER_TOKEN"
-	usernameEnvName                                    = "USERNAME"
-	passwordEnvName                                    = "PASSWORD"
-	apiResponseTimeoutEnvName                          = "API_RESPONSE_TIMEOUT"
-	expectedStatusCodeEnvName                          = "EXPECTED_STATUS_CODE"
-	expectedBodyEnvName                                = "EXPECTED_BODY"
-	logzioMetricsListenerEnvName                       = "LOGZIO_METRICS_LISTENER"
-	logzioMetricsTokenEnvName                          = "LOGZIO_METRICS_TOKEN"
-	awsRegionEnvName                                   = "AWS_REGION"
-	awsLambdaFunctionNameEnvName                       = "AWS_LAMBDA_FUNCTION_NAME"
-	meterName                                          = "api_status"
-	statusMetricName                                   = meterName + "_status"
-	responseTimeMetricName                             = meterName + "_response_time"
-	responseBodyLengthMetricName                       = meterName + "_response_body_length"
-	statusObserverDescription                          = "API status"
-	statusMetricValue                                  = 1
-	awsRegionLabelName                                 = "aws_region"
-	awsLambdaFunctionLabelName                         = "aws_lambda_function"
-	urlLabelName                                       = "url"
-	methodLabelName                                    = "method"
-	statusMetricStatusLabelName                        = "status"
-	responseTimeoutStatusMetricStatusLabelValue        = "response_timeout"
-	connectionFailedStatusMetricStatusLabelValue       = "connection_failed"
-	readResponseBodyFailedStatusMetricStatusLabelValue = "read_response_body_failed"
-	noMatchStatusCodeStatusMetricStatusLabelValue      = "no_match_status_code"
-	noMatchResponseBodyStatusMetricStatusLabelValue    = "no_match_response_body"
-	successStatusMetricStatusLabelValue                = "success"
-	statusMetricResponseTimeoutLabelName               = "response_timeout"
-	statusMetricResponseTimeoutUnitLabelName           = "response_timeout_unit"
-	statusMetricResponseTimeoutUnitLabelValue          = "seconds"
-	statusMetricErrorLabelName                         = "error"
-	statusMetricResponseStatusCodeLabelName            = "response_status_code"
-	statusMetricExpectedResponseStatusCodeLabelName    = "expected_response_status_code"
-	statusMetricResponseBodyLabelName                  = "response_body"
-	statusMetricExpectedResponseBodyLabelName          = "expected_response_body"
-	unitLabelName                                      = "unit"
-	responseTimeMetricUnitLabelValue                   = "milliseconds"
-	responseBodyLengthMetricUnitLabelValue             = "bytes"
-	geoHashLabelName                                   = "geohash"
-	longitudeIndex                                     = 0
-	latitudeIndex                                      = 1
+	apiUrlEnvName                                           = "API_URL"
+	methodEnvName                                           = "METHOD"
+	headersEnvName                                          = "HEADERS"
+	bodyEnvName                                             = "BODY"
+	bearerTokenEnvName                                      = "BEARER_TOKEN"
+	usernameEnvName                                         = "USERNAME"
+	passwordEnvName                                         = "PASSWORD"
+	apiResponseTimeoutEnvName                               = "API_RESPONSE_TIMEOUT"
+	expectedStatusCodeEnvName                               = "EXPECTED_STATUS_CODE"
+	expectedBodyEnvName                                     = "EXPECTED_BODY"
+	expectedBodyMatchEnvName                                = "EXPECTED_BODY_MATCH"
+	certificateExpiryThresholdDaysEnvName                   = "CERTIFICATE_EXPIRY_THRESHOLD_DAYS"
+	slowResponseThresholdEnvName                            = "SLOW_RESPONSE_THRESHOLD"
+	logzioMetricsListenerEnvName                            = "LOGZIO_METRICS_LISTENER"
+	logzioMetricsTokenEnvName                               = "LOGZIO_METRICS_TOKEN"
+	checksConcurrencyEnvName                                = "CHECKS_CONCURRENCY"
+	defaultChecksConcurrency                                = 10
+	modeEnvName                                             = "MODE"
+	modeFlagName                                            = "mode"
+	lambdaMode                                              = "lambda"
+	daemonMode                                              = "daemon"
+	awsRegionEnvName                                        = "AWS_REGION"
+	awsLambdaFunctionNameEnvName                            = "AWS_LAMBDA_FUNCTION_NAME"
+	meterName                                               = "api_status"
+	statusMetricName                                        = meterName + "_status"
+	responseTimeMetricName                                  = meterName + "_response_time"
+	responseBodyLengthMetricName                            = meterName + "_response_body_length"
+	dnsLookupTimeMetricName                                 = meterName + "_dns_lookup_time"
+	connectTimeMetricName                                   = meterName + "_connect_time"
+	tlsHandshakeTimeMetricName                              = meterName + "_tls_handshake_time"
+	timeToFirstByteMetricName                               = meterName + "_time_to_first_byte"
+	bodyDownloadTimeMetricName                              = meterName + "_body_download_time"
+	certificateExpiryMetricName                             = meterName + "_certificate_expiry"
+	certificateHostMatchMetricName                          = meterName + "_certificate_host_match"
+	statusObserverDescription                               = "API status"
+	statusMetricValue                                       = 1
+	awsRegionLabelName                                      = "aws_region"
+	awsLambdaFunctionLabelName                              = "aws_lambda_function"
+	checkNameLabelName                                      = "check_name"
+	urlLabelName                                            = "url"
+	methodLabelName                                         = "method"
+	statusMetricStatusLabelName                             = "status"
+	responseTimeoutStatusMetricStatusLabelValue             = "response_timeout"
+	connectionFailedStatusMetricStatusLabelValue            = "connection_failed"
+	readResponseBodyFailedStatusMetricStatusLabelValue      = "read_response_body_failed"
+	noMatchStatusCodeStatusMetricStatusLabelValue           = "no_match_status_code"
+	noMatchResponseHeaderStatusMetricStatusLabelValue       = "no_match_response_header"
+	noMatchResponseBodyStatusMetricStatusLabelValue         = "no_match_response_body"
+	noMatchResponseBodyContainsStatusMetricStatusLabelValue = "no_match_response_body_contains"
+	noMatchResponseBodyRegexStatusMetricStatusLabelValue    = "no_match_response_body_regex"
+	noMatchResponseBodyJSONPathStatusMetricStatusLabelValue = "no_match_response_body_jsonpath"
+	slowResponseStatusMetricStatusLabelValue                = "slow_response"
+	certificateExpiringStatusMetricStatusLabelValue         = "certificate_expiring"
+	successStatusMetricStatusLabelValue                     = "success"
+	statusMetricResponseTimeoutLabelName                    = "response_timeout"
+	statusMetricResponseTimeoutUnitLabelName                = "response_timeout_unit"
+	statusMetricResponseTimeoutUnitLabelValue               = "seconds"
+	statusMetricTimeoutPhaseLabelName                       = "timeout_phase"
+	statusMetricErrorLabelName                              = "error"
+	statusMetricResponseStatusCodeLabelName                 = "response_status_code"
+	statusMetricExpectedResponseStatusCodeLabelName         = "expected_response_status_code"
+	statusMetricResponseHeaderLabelName                     = "response_header"
+	statusMetricResponseHeaderValueLabelName                = "response_header_value"
+	statusMetricExpectedResponseHeaderLabelName             = "expected_response_header"
+	statusMetricResponseHeaderAssertionLabelName            = "response_header_assertion"
+	statusMetricResponseBodyLabelName                       = "response_body"
+	statusMetricExpectedResponseBodyLabelName               = "expected_response_body"
+	statusMetricSlowResponseSeverityLabelName               = "slow_response_severity"
+	statusMetricResponseTimeThresholdLabelName              = "response_time_threshold"
+	statusMetricResponseTimeThresholdUnitLabelName          = "response_time_threshold_unit"
+	warningSlowResponseSeverityLabelValue                   = "warning"
+	criticalSlowResponseSeverityLabelValue                  = "critical"
+	statusMetricCertificateExpiryDaysLabelName              = "certificate_expiry_days"
+	statusMetricCertificateExpiryThresholdDaysLabelName     = "certificate_expiry_threshold_days"
+	certificateIndexLabelName                               = "certificate_index"
+	certificateTypeLabelName                                = "certificate_type"
+	certificateSubjectLabelName                             = "certificate_subject"
+	certificateIssuerLabelName                              = "certificate_issuer"
+	certificateHostLabelName                                = "certificate_host"
+	unitLabelName                                           = "unit"
+	responseTimeMetricUnitLabelValue                        = "milliseconds"
+	responseBodyLengthMetricUnitLabelValue                  = "bytes"
+	certificateExpiryMetricUnitLabelValue                   = "days"
+	geoHashLabelName                                        = "geohash"
+	longitudeIndex                                          = 0
+	latitudeIndex                                           = 1
 )
 
 var (
-	debugLogger         = log.New(os.Stdout, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
-	infoLogger          = log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	errorLogger         = log.New(os.Stdout, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
 	regionToGeoLocation = map[string][]float64{
 		"us-east-1":      {-78.024902, 37.926868},  // N. Virginia
 		"us-east-2":      {-82.996216, 40.367474},  // Ohio
@@ -95,180 +135,506 @@ var (
 		"eu-north-1":     {18.063240, 59.334591},   // Stockholm
 		"sa-east-1":      {-46.625290, -23.533773}, // Sao Paulo
 	}
-	awsRegion string
-	geoHash   string
+	awsRegion        string
+	geoHash          string
+	supportedMethods = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodOptions,
+		http.MethodTrace,
+	}
 )
 
 type logzioApiStatus struct {
-	ctx                        context.Context
-	logzioMetricsListener      string
-	logzioMetricsToken         string
-	url                        string
-	method                     string
-	headers                    map[string]string
-	body                       string
-	responseTimeout            time.Duration
-	bearerToken                string
-	username                   string
-	password                   string
-	expectedResponseStatusCode int
-	expectedResponseBody       string
+	ctx                   context.Context
+	logzioMetricsListener string
+	logzioMetricsToken    string
+	metricsExporters      []*namedMetricsExporter
+	logShipper            *logzioLogShipper
+	stateStore            stateStore
+	notifiers             []*webhookNotifier
+	checks                []*apiCheck
+	checksConcurrency     int
+}
+
+type apiCheck struct {
+	name                           string
+	url                            string
+	method                         string
+	headers                        map[string]string
+	body                           string
+	responseTimeout                time.Duration
+	connectTimeout                 time.Duration
+	tlsHandshakeTimeout            time.Duration
+	transport                      http.RoundTripper
+	bearerToken                    string
+	username                       string
+	password                       string
+	oauth2                         *oauth2TokenSource
+	sigV4                          *sigV4Signer
+	expectedResponseStatusCode     *statusCodeExpression
+	responseHeaderAssertions       []*responseHeaderAssertion
+	responseBodyAssertions         []*responseBodyAssertion
+	warningResponseTimeThreshold   time.Duration
+	criticalResponseTimeThreshold  time.Duration
+	certificateExpiryThresholdDays int
+	interval                       time.Duration
+	cronSchedule                   *cronSchedule
+	retryAttempts                  int
+	retryBackoff                   time.Duration
+	retryableOutcomes              map[string]bool
+	responseBodyLabelMode          string
+	errorLabelMode                 string
+	labelValueMaxLength            int
 }
 
+// A gauge's value and labels for a check's run, which are observed when the metrics are collected
 type int64GaugeObserver struct {
-	name                  string
-	int64ObserverCallback func(context.Context, metric.Int64ObserverResult)
-	description           string
+	name        string
+	value       int64
+	attributes  []attribute.KeyValue
+	description string
 }
 
 type float64GaugeObserver struct {
-	name                    string
-	float64ObserverCallback func(context.Context, metric.Float64ObserverResult)
-	description             string
+	name        string
+	value       float64
+	attributes  []attribute.KeyValue
+	description string
 }
 
+// Gauge observers of the same metric, since the meter keeps only the first callback registered per metric name
+type int64GaugeObserverGroup []*int64GaugeObserver
+
+type float64GaugeObserverGroup []*float64GaugeObserver
+
 type metricRegister interface {
 	registerMetric(metric.Meter)
 }
 
-func newLogzioApiStatus(ctx context.Context) (*logzioApiStatus, error) {
-	logzioMetricsListener := os.Getenv(logzioMetricsListenerEnvName)
-	if logzioMetricsListener == "" {
-		return nil, fmt.Errorf("%s must not be empty", logzioMetricsListenerEnvName)
+// The response of a check's request, as it was read
+type checkResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// The outcome of running a check once
+type checkRun struct {
+	gaugeObservers []metricRegister
+	// The status, its labels and the metric values of the gauge observers
+	result   *checkResult
+	response *checkResponse
+	err      error
+	start    time.Time
+	duration time.Duration
+}
+
+// The mode is lambda or daemon, where the metrics can be scraped without exporters
+func newLogzioApiStatus(ctx context.Context, mode string) (*logzioApiStatus, error) {
+	checks, err := getApiChecks()
+	if err != nil {
+		return nil, fmt.Errorf("error getting api checks: %v", err)
+	}
+
+	checksConcurrency, err := getChecksConcurrency()
+	if err != nil {
+		return nil, err
+	}
+
+	apiStatus := &logzioApiStatus{
+		ctx:                   ctx,
+		logzioMetricsListener: os.Getenv(logzioMetricsListenerEnvName),
+		logzioMetricsToken:    os.Getenv(logzioMetricsTokenEnvName),
+		checks:                checks,
+		checksConcurrency:     checksConcurrency,
+	}
+
+	if apiStatus.metricsExporters, err = apiStatus.getMetricsExporters(); err != nil {
+		return nil, fmt.Errorf("error getting metrics exporters: %v", err)
+	}
+
+	// Otherwise the metrics would not be sent anywhere
+	if len(apiStatus.metricsExporters) == 0 && mode != daemonMode {
+		return nil, fmt.Errorf("%s can be %s only in %s mode, where the metrics are scraped", metricsExportersEnvName, noMetricsExporters, daemonMode)
+	}
+
+	if apiStatus.logShipper, err = getLogzioLogShipper(); err != nil {
+		return nil, fmt.Errorf("error getting logs shipper: %v", err)
+	}
+
+	if apiStatus.stateStore, err = getStateStore(); err != nil {
+		return nil, fmt.Errorf("error getting state store: %v", err)
+	}
+
+	if apiStatus.stateStore != nil && !apiStatus.stateStore.persistent() && isLambdaFunction() {
+		logger.Warn(fmt.Sprintf("The %s state store is lost when the Lambda function's instance is replaced, so the checks' states start over after a cold start. Use the %s state store, or a %s on a mounted file system, to keep them.",
+			os.Getenv(stateStoreEnvName), s3StateStoreType, stateFileEnvName))
+	}
+
+	if apiStatus.notifiers, err = getWebhookNotifiers(); err != nil {
+		return nil, fmt.Errorf("error getting notifiers: %v", err)
 	}
 
-	logzioMetricsToken := os.Getenv(logzioMetricsTokenEnvName)
-	if logzioMetricsToken == "" {
-		return nil, fmt.Errorf("%s must not be empty", logzioMetricsTokenEnvName)
+	// Status changes are found by comparing to the last status in the state store
+	if len(apiStatus.notifiers) > 0 && apiStatus.stateStore == nil {
+		return nil, fmt.Errorf("%s must be set to send notifications", stateStoreEnvName)
 	}
 
-	apiURL := os.Getenv(apiUrlEnvName)
-	if apiURL == "" {
-		return nil, fmt.Errorf("%s must not be empty", apiUrlEnvName)
+	// A state that is lost on cold starts would repeat failure notifications after each cold start, and miss recoveries
+	// that another instance of the function sees
+	if len(apiStatus.notifiers) > 0 && !apiStatus.stateStore.persistent() && isLambdaFunction() {
+		logger.Warn(fmt.Sprintf("Notifications are disabled, since the %s state store is not persistent in a Lambda function. Use the %s state store to send notifications.",
+			os.Getenv(stateStoreEnvName), s3StateStoreType))
+		apiStatus.notifiers = nil
 	}
 
-	parsedURL, err := url.Parse(apiURL)
+	return apiStatus, nil
+}
+
+// Whether the process is a Lambda function's instance, which is replaced on cold starts
+func isLambdaFunction() bool {
+	return os.Getenv(awsLambdaFunctionNameEnvName) != ""
+}
+
+func getChecksConcurrency() (int, error) {
+	checksConcurrencyString := os.Getenv(checksConcurrencyEnvName)
+	if checksConcurrencyString == "" {
+		return defaultChecksConcurrency, nil
+	}
+
+	checksConcurrency, err := strconv.Atoi(checksConcurrencyString)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing url %s: %v", apiURL, err)
+		return 0, fmt.Errorf("%s must be a number", checksConcurrencyEnvName)
 	}
 
-	method := os.Getenv(methodEnvName)
-	if method != http.MethodGet && method != http.MethodPost {
-		return nil, fmt.Errorf("%s must be GET or POST", methodEnvName)
+	if checksConcurrency < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", checksConcurrencyEnvName)
 	}
 
-	headers, err := getApiRequestHeaders()
+	return checksConcurrency, nil
+}
+
+func isSupportedMethod(method string) bool {
+	for _, supportedMethod := range supportedMethods {
+		if method == supportedMethod {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newApiCheck(config *checkConfig) (*apiCheck, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("check name must not be empty")
+	}
+
+	if config.URL == "" {
+		return nil, fmt.Errorf("url must not be empty")
+	}
+
+	parsedURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url %s: %v", config.URL, err)
+	}
+
+	if !isSupportedMethod(config.Method) {
+		return nil, fmt.Errorf("method must be one of %s", strings.Join(supportedMethods, ", "))
+	}
+
+	responseTimeout, err := parseOptionalTimeout(config.Timeout)
 	if err != nil {
-		return nil, fmt.Errorf("error getting api headers: %v", err)
+		return nil, fmt.Errorf("error parsing timeout: %v", err)
 	}
 
-	responseTimeout, err := strconv.Atoi(os.Getenv(apiResponseTimeoutEnvName))
+	if responseTimeout == 0 {
+		return nil, fmt.Errorf("timeout must be set")
+	}
+
+	connectTimeout, err := parseOptionalTimeout(config.ConnectTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("%s must be a number", apiResponseTimeoutEnvName)
+		return nil, fmt.Errorf("error parsing connect timeout: %v", err)
 	}
 
-	if responseTimeout < 1 {
-		return nil, fmt.Errorf("%s must be a positive number", apiResponseTimeoutEnvName)
+	tlsHandshakeTimeout, err := parseOptionalTimeout(config.TLSHandshakeTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing TLS handshake timeout: %v", err)
 	}
 
-	expectedResponseStatusCode, err := strconv.Atoi(os.Getenv(expectedStatusCodeEnvName))
+	warningResponseTimeThreshold, err := parseOptionalDuration(config.SlowResponse.Warning)
 	if err != nil {
-		return nil, fmt.Errorf("%s must be a number", expectedStatusCodeEnvName)
+		return nil, fmt.Errorf("error parsing slow response warning threshold: %v", err)
+	}
+
+	criticalResponseTimeThreshold, err := parseOptionalDuration(config.SlowResponse.Critical)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing slow response critical threshold: %v", err)
+	}
+
+	if warningResponseTimeThreshold != 0 && criticalResponseTimeThreshold != 0 && warningResponseTimeThreshold >= criticalResponseTimeThreshold {
+		return nil, fmt.Errorf("slow response warning threshold must be lower than the critical threshold")
+	}
+
+	if config.CertificateExpiryThresholdDays < 0 {
+		return nil, fmt.Errorf("certificate expiry threshold days must not be negative")
+	}
+
+	expectedResponseStatusCode, err := newStatusCodeExpression(config.ExpectedStatusCode)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing expected status code: %v", err)
+	}
+
+	interval, err := parseOptionalDuration(config.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing interval: %v", err)
+	}
+
+	var schedule *cronSchedule
+
+	if config.Cron != "" {
+		if interval != 0 {
+			return nil, fmt.Errorf("only one of interval and cron can be set")
+		}
+
+		if schedule, err = newCronSchedule(config.Cron); err != nil {
+			return nil, err
+		}
+
+		if schedule.next(time.Now()).IsZero() {
+			return nil, fmt.Errorf("cron expression %s never matches", config.Cron)
+		}
+	} else if interval == 0 {
+		interval = defaultCheckInterval
+	}
+
+	check := &apiCheck{
+		name:                           config.Name,
+		url:                            parsedURL.String(),
+		method:                         config.Method,
+		headers:                        config.Headers,
+		body:                           config.Body,
+		responseTimeout:                responseTimeout,
+		connectTimeout:                 connectTimeout,
+		tlsHandshakeTimeout:            tlsHandshakeTimeout,
+		bearerToken:                    config.Auth.BearerToken,
+		username:                       config.Auth.Username,
+		password:                       config.Auth.Password,
+		expectedResponseStatusCode:     expectedResponseStatusCode,
+		warningResponseTimeThreshold:   warningResponseTimeThreshold,
+		criticalResponseTimeThreshold:  criticalResponseTimeThreshold,
+		certificateExpiryThresholdDays: config.CertificateExpiryThresholdDays,
+		interval:                       interval,
+		cronSchedule:                   schedule,
+		responseBodyLabelMode:          config.LabelValues.ResponseBody,
+		errorLabelMode:                 config.LabelValues.Error,
+		labelValueMaxLength:            config.LabelValues.MaxLength,
+	}
+
+	if check.responseBodyLabelMode == "" {
+		check.responseBodyLabelMode = defaultLabelValueMode
+	}
+
+	if check.errorLabelMode == "" {
+		check.errorLabelMode = defaultLabelValueMode
+	}
+
+	if check.labelValueMaxLength == 0 {
+		check.labelValueMaxLength = defaultLabelValueMaxLength
+	}
+
+	if err = validateLabelValueMode(check.responseBodyLabelMode); err != nil {
+		return nil, fmt.Errorf("error in response body label: %v", err)
+	}
+
+	if err = validateLabelValueMode(check.errorLabelMode); err != nil {
+		return nil, fmt.Errorf("error in error label: %v", err)
+	}
+
+	if check.labelValueMaxLength < 0 {
+		return nil, fmt.Errorf("label value max length must not be negative")
+	}
+
+	if err = check.setRetry(config.Retry); err != nil {
+		return nil, err
 	}
 
-	if expectedResponseStatusCode < 100 || expectedResponseStatusCode > 599 {
-		return nil, fmt.Errorf("%s must be a between 100 and 599 (inclusive)", apiResponseTimeoutEnvName)
+	if err = check.setTransport(config.TLS); err != nil {
+		return nil, fmt.Errorf("error in TLS config: %v", err)
+	}
+
+	if err = validateAuthConfig(config.Auth); err != nil {
+		return nil, err
+	}
+
+	if config.Auth.OAuth2 != nil {
+		if check.oauth2, err = getOAuth2TokenSource(config.Auth.OAuth2); err != nil {
+			return nil, fmt.Errorf("error in OAuth2 config: %v", err)
+		}
+	}
+
+	if config.Auth.SigV4 != nil {
+		if check.sigV4, err = newSigV4Signer(config.Auth.SigV4); err != nil {
+			return nil, fmt.Errorf("error in SigV4 config: %v", err)
+		}
+	}
+
+	for _, assertionConf := range config.HeaderAssertions {
+		responseHeaderAssertion, err := newResponseHeaderAssertion(assertionConf)
+		if err != nil {
+			return nil, fmt.Errorf("error creating response header assertion: %v", err)
+		}
+
+		check.responseHeaderAssertions = append(check.responseHeaderAssertions, responseHeaderAssertion)
+	}
+
+	responseBodyAssertionConfigs := config.BodyAssertions
+	if config.ExpectedBody != nil {
+		responseBodyAssertionConfigs = append([]*assertionConfig{{Type: equalsAssertionType, Value: *config.ExpectedBody}}, responseBodyAssertionConfigs...)
+	}
+
+	// The response body of HEAD requests is not read, an empty expected body (the default of the environment variables) is ignored
+	if config.Method == http.MethodHead {
+		if len(config.BodyAssertions) > 0 || (config.ExpectedBody != nil && *config.ExpectedBody != "") {
+			return nil, fmt.Errorf("response body assertions are not supported for %s requests", http.MethodHead)
+		}
+
+		responseBodyAssertionConfigs = nil
 	}
 
-	return &logzioApiStatus{
-		ctx:                        ctx,
-		logzioMetricsListener:      logzioMetricsListener,
-		logzioMetricsToken:         logzioMetricsToken,
-		url:                        parsedURL.String(),
-		method:                     method,
-		headers:                    headers,
-		body:                       os.Getenv(bodyEnvName),
-		responseTimeout:            time.Duration(responseTimeout) * time.Second,
-		bearerToken:                os.Getenv(bearerTokenEnvName),
-		username:                   os.Getenv(usernameEnvName),
-		password:                   os.Getenv(passwordEnvName),
-		expectedResponseStatusCode: expectedResponseStatusCode,
-		expectedResponseBody:       os.Getenv(expectedBodyEnvName),
-	}, nil
+	for _, assertionConf := range responseBodyAssertionConfigs {
+		responseBodyAssertion, err := newResponseBodyAssertion(assertionConf)
+		if err != nil {
+			return nil, fmt.Errorf("error creating response body assertion: %v", err)
+		}
+
+		check.responseBodyAssertions = append(check.responseBodyAssertions, responseBodyAssertion)
+	}
+
+	return check, nil
 }
 
-func newInt64GaugeObserver(name string, observerCallback func(context.Context, metric.Int64ObserverResult), description string) *int64GaugeObserver {
+func newInt64GaugeObserver(name string, value int64, attributes []attribute.KeyValue, description string) *int64GaugeObserver {
 	return &int64GaugeObserver{
-		name:                  name,
-		int64ObserverCallback: observerCallback,
-		description:           description,
+		name:        name,
+		value:       value,
+		attributes:  attributes,
+		description: description,
 	}
 }
 
-func newFloat64GaugeObserver(name string, observerCallback func(context.Context, metric.Float64ObserverResult), description string) *float64GaugeObserver {
+func newFloat64GaugeObserver(name string, value float64, attributes []attribute.KeyValue, description string) *float64GaugeObserver {
 	return &float64GaugeObserver{
-		name:                    name,
-		float64ObserverCallback: observerCallback,
-		description:             description,
+		name:        name,
+		value:       value,
+		attributes:  attributes,
+		description: description,
 	}
 }
 
+func (igo *int64GaugeObserver) observe(result metric.Int64ObserverResult) {
+	debugLogger.Printf("Observing %s...\n", igo.name)
+	result.Observe(igo.value, igo.attributes...)
+}
+
+func (fgo *float64GaugeObserver) observe(result metric.Float64ObserverResult) {
+	debugLogger.Printf("Observing %s...\n", fgo.name)
+	result.Observe(fgo.value, fgo.attributes...)
+}
+
 func (igo *int64GaugeObserver) registerMetric(meter metric.Meter) {
+	int64GaugeObserverGroup{igo}.registerMetric(meter)
+}
+
+func (fgo *float64GaugeObserver) registerMetric(meter metric.Meter) {
+	float64GaugeObserverGroup{fgo}.registerMetric(meter)
+}
+
+func (igog int64GaugeObserverGroup) registerMetric(meter metric.Meter) {
 	_ = metric.Must(meter).NewInt64GaugeObserver(
-		igo.name,
-		igo.int64ObserverCallback,
-		metric.WithDescription(igo.description),
+		igog[0].name,
+		func(_ context.Context, result metric.Int64ObserverResult) {
+			for _, gaugeObserver := range igog {
+				gaugeObserver.observe(result)
+			}
+		},
+		metric.WithDescription(igog[0].description),
 	)
 }
 
-func (fgo *float64GaugeObserver) registerMetric(meter metric.Meter) {
+func (fgog float64GaugeObserverGroup) registerMetric(meter metric.Meter) {
 	_ = metric.Must(meter).NewFloat64GaugeObserver(
-		fgo.name,
-		fgo.float64ObserverCallback,
-		metric.WithDescription(fgo.description),
+		fgog[0].name,
+		func(_ context.Context, result metric.Float64ObserverResult) {
+			for _, gaugeObserver := range fgog {
+				gaugeObserver.observe(result)
+			}
+		},
+		metric.WithDescription(fgog[0].description),
 	)
 }
 
-func (las *logzioApiStatus) createApiHttpRequest() (*http.Request, error) {
+func (ac *apiCheck) createApiHttpRequest(ctx context.Context) (*http.Request, error) {
 	debugLogger.Println("Creating API HTTP request...")
 
 	var bodyReader io.Reader
 
-	if las.body != "" {
-		bodyReader = strings.NewReader(las.body)
+	if ac.body != "" {
+		bodyReader = strings.NewReader(ac.body)
 	}
 
-	request, err := http.NewRequest(las.method, las.url, bodyReader)
+	request, err := http.NewRequestWithContext(ctx, ac.method, ac.url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
-	if las.bearerToken != "" {
-		bearer := "Bearer " + strings.Trim(las.bearerToken, "\n")
+	if ac.bearerToken != "" {
+		bearer := "Bearer " + strings.Trim(ac.bearerToken, "\n")
 		request.Header.Add("Authorization", bearer)
 	}
 
-	for key, value := range las.headers {
+	if ac.oauth2 != nil {
+		accessToken, err := ac.oauth2.getToken(ctx)
+		if err != nil {
+			return nil, &authError{err: err}
+		}
+
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	for key, value := range ac.headers {
 		request.Header.Add(key, value)
 		if key == "Host" {
 			request.Host = value
 		}
 	}
 
-	if las.username != "" || las.password != "" {
-		request.SetBasicAuth(las.username, las.password)
+	if ac.username != "" || ac.password != "" {
+		request.SetBasicAuth(ac.username, ac.password)
+	}
+
+	// The request is signed last, since the signature covers its headers
+	if ac.sigV4 != nil {
+		credentials, err := getAwsCredentials()
+		if err != nil {
+			return nil, &authError{err: err}
+		}
+
+		ac.sigV4.sign(request, []byte(ac.body), credentials, time.Now())
 	}
 
 	return request, nil
 }
 
-func (las *logzioApiStatus) getApiHttpResponse(request *http.Request) (*http.Response, float64, error) {
+func (ac *apiCheck) getApiHttpResponse(request *http.Request) (*http.Response, float64, error) {
 	debugLogger.Println("Getting API HTTP response...")
 
-	client := &http.Client{
-		Transport: http.DefaultTransport,
-		Timeout:   las.responseTimeout * time.Second,
-	}
+	// The request's context has the check's timeout, which also bounds reading the response body
+	client := &http.Client{Transport: ac.getTransport()}
 	start := time.Now()
 	response, err := client.Do(request)
 	end := time.Now()
@@ -277,140 +643,230 @@ func (las *logzioApiStatus) getApiHttpResponse(request *http.Request) (*http.Res
 	return response, responseTime, err
 }
 
-func (las *logzioApiStatus) getResponseErrorStatusGaugeObserver(responseError error) *int64GaugeObserver {
+func (ac *apiCheck) getResponseErrorStatusGaugeObserver(responseError error) *int64GaugeObserver {
 	if responseError == nil {
 		debugLogger.Println("No response error status")
 		return nil
 	}
 
 	if timeoutError, ok := responseError.(net.Error); ok && timeoutError.Timeout() {
-		observerCallback := func(_ context.Context, result metric.Int64ObserverResult) {
-			debugLogger.Println("Running response timeout status observer callback...")
-
-			result.Observe(statusMetricValue,
-				attribute.String(urlLabelName, las.url),
-				attribute.String(methodLabelName, las.method),
-				attribute.String(statusMetricStatusLabelName, responseTimeoutStatusMetricStatusLabelValue),
-				attribute.Float64(statusMetricResponseTimeoutLabelName, float64(las.responseTimeout/time.Second)),
-				attribute.String(statusMetricResponseTimeoutUnitLabelName, statusMetricResponseTimeoutUnitLabelValue),
-				attribute.String(statusMetricErrorLabelName, responseError.Error()))
+		attributes := []attribute.KeyValue{
+			attribute.String(checkNameLabelName, ac.name),
+			attribute.String(urlLabelName, ac.getRedactedURL()),
+			attribute.String(methodLabelName, ac.method),
+			attribute.String(statusMetricStatusLabelName, responseTimeoutStatusMetricStatusLabelValue),
+			attribute.Float64(statusMetricResponseTimeoutLabelName, ac.responseTimeout.Seconds()),
+			attribute.String(statusMetricResponseTimeoutUnitLabelName, statusMetricResponseTimeoutUnitLabelValue),
+			attribute.String(statusMetricTimeoutPhaseLabelName, getTimeoutPhase(responseError)),
 		}
 
-		return newInt64GaugeObserver(statusMetricName, observerCallback, statusObserverDescription)
+		return newInt64GaugeObserver(statusMetricName, statusMetricValue, append(attributes, ac.getRequestErrorLabels(responseError)...), statusObserverDescription)
 	}
 
-	observerCallback := func(_ context.Context, result metric.Int64ObserverResult) {
-		debugLogger.Println("Running connection failed status observer callback...")
-
-		result.Observe(statusMetricValue,
-			attribute.String(urlLabelName, las.url),
-			attribute.String(methodLabelName, las.method),
-			attribute.String(statusMetricStatusLabelName, connectionFailedStatusMetricStatusLabelValue),
-			attribute.String(statusMetricErrorLabelName, responseError.Error()))
+	attributes := []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, connectionFailedStatusMetricStatusLabelValue),
 	}
 
-	return newInt64GaugeObserver(statusMetricName, observerCallback, statusObserverDescription)
+	return newInt64GaugeObserver(statusMetricName, statusMetricValue, append(attributes, ac.getRequestErrorLabels(responseError)...), statusObserverDescription)
 }
 
-func (las *logzioApiStatus) getReadResponseBodyErrorStatusGaugeObserver(responseStatusCode int, readResponseBodyError error) *int64GaugeObserver {
+func (ac *apiCheck) getReadResponseBodyErrorStatusGaugeObserver(responseStatusCode int, readResponseBodyError error) *int64GaugeObserver {
 	if readResponseBodyError == nil {
 		debugLogger.Println("No read response body error status")
 		return nil
 	}
 
-	observerCallback := func(_ context.Context, result metric.Int64ObserverResult) {
-		debugLogger.Println("Running read response body failed status observer callback...")
-
-		result.Observe(statusMetricValue,
-			attribute.String(urlLabelName, las.url),
-			attribute.String(methodLabelName, las.method),
-			attribute.String(statusMetricStatusLabelName, readResponseBodyFailedStatusMetricStatusLabelValue),
-			attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
-			attribute.String(statusMetricErrorLabelName, readResponseBodyError.Error()))
+	attributes := []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, readResponseBodyFailedStatusMetricStatusLabelValue),
+		attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
 	}
 
-	return newInt64GaugeObserver(statusMetricName, observerCallback, statusObserverDescription)
+	return newInt64GaugeObserver(statusMetricName, statusMetricValue, append(attributes, ac.getRequestErrorLabels(readResponseBodyError)...), statusObserverDescription)
 }
 
-func (las *logzioApiStatus) getNoMatchStatusGaugeObserver(responseStatusCode int, responseBodyBytes []byte) *int64GaugeObserver {
-	if responseStatusCode != las.expectedResponseStatusCode {
-		observerCallback := func(_ context.Context, result metric.Int64ObserverResult) {
-			debugLogger.Println("Running no match status code status observer callback...")
+func (ac *apiCheck) getNoMatchStatusGaugeObserver(responseStatusCode int, responseHeader http.Header, responseBodyBytes []byte) *int64GaugeObserver {
+	if !ac.expectedResponseStatusCode.matches(responseStatusCode) {
+		return newInt64GaugeObserver(statusMetricName, statusMetricValue, []attribute.KeyValue{
+			attribute.String(checkNameLabelName, ac.name),
+			attribute.String(urlLabelName, ac.getRedactedURL()),
+			attribute.String(methodLabelName, ac.method),
+			attribute.String(statusMetricStatusLabelName, noMatchStatusCodeStatusMetricStatusLabelValue),
+			attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
+			attribute.String(statusMetricExpectedResponseStatusCodeLabelName, ac.expectedResponseStatusCode.expression),
+		}, statusObserverDescription)
+	}
 
-			result.Observe(statusMetricValue,
-				attribute.String(urlLabelName, las.url),
-				attribute.String(methodLabelName, las.method),
-				attribute.String(statusMetricStatusLabelName, noMatchStatusCodeStatusMetricStatusLabelValue),
-				attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
-				attribute.Int(statusMetricExpectedResponseStatusCodeLabelName, las.expectedResponseStatusCode))
+	for _, responseHeaderAssertion := range ac.responseHeaderAssertions {
+		if statusGaugeObserver := ac.getNoMatchResponseHeaderStatusGaugeObserver(responseStatusCode, responseHeader, responseHeaderAssertion); statusGaugeObserver != nil {
+			return statusGaugeObserver
 		}
-
-		return newInt64GaugeObserver(statusMetricName, observerCallback, statusObserverDescription)
 	}
 
-	if string(responseBodyBytes) != las.expectedResponseBody {
-		observerCallback := func(_ context.Context, result metric.Int64ObserverResult) {
-			debugLogger.Println("Running no match response body status observer callback...")
-
-			result.Observe(statusMetricValue,
-				attribute.String(urlLabelName, las.url),
-				attribute.String(methodLabelName, las.method),
-				attribute.String(statusMetricStatusLabelName, noMatchResponseBodyStatusMetricStatusLabelValue),
-				attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
-				attribute.String(statusMetricResponseBodyLabelName, string(responseBodyBytes)),
-				attribute.String(statusMetricExpectedResponseBodyLabelName, las.expectedResponseBody))
+	for _, responseBodyAssertion := range ac.responseBodyAssertions {
+		if statusGaugeObserver := ac.getNoMatchResponseBodyStatusGaugeObserver(responseStatusCode, responseBodyBytes, responseBodyAssertion); statusGaugeObserver != nil {
+			return statusGaugeObserver
 		}
-
-		return newInt64GaugeObserver(statusMetricName, observerCallback, statusObserverDescription)
 	}
 
 	debugLogger.Println("No no match status")
 	return nil
 }
 
-func (las *logzioApiStatus) getSuccessStatusGaugeObserver(responseStatusCode int) *int64GaugeObserver {
-	observerCallback := func(_ context.Context, result metric.Int64ObserverResult) {
-		debugLogger.Println("Running success status observer callback...")
-		result.Observe(statusMetricValue,
-			attribute.String(urlLabelName, las.url),
-			attribute.String(methodLabelName, las.method),
-			attribute.String(statusMetricStatusLabelName, successStatusMetricStatusLabelValue),
-			attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode))
+func (ac *apiCheck) getNoMatchResponseHeaderStatusGaugeObserver(responseStatusCode int, responseHeader http.Header, responseHeaderAssertion *responseHeaderAssertion) *int64GaugeObserver {
+	if responseHeaderAssertion.assert(responseHeader) {
+		return nil
 	}
 
-	return newInt64GaugeObserver(statusMetricName, observerCallback, statusObserverDescription)
+	return newInt64GaugeObserver(statusMetricName, statusMetricValue, []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, noMatchResponseHeaderStatusMetricStatusLabelValue),
+		attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
+		attribute.String(statusMetricResponseHeaderLabelName, responseHeaderAssertion.name),
+		attribute.String(statusMetricResponseHeaderAssertionLabelName, responseHeaderAssertion.assertionType),
+		attribute.String(statusMetricResponseHeaderValueLabelName, secretRedactor.redactHeaderValue(responseHeaderAssertion.name, strings.Join(responseHeader.Values(responseHeaderAssertion.name), ", "))),
+		attribute.String(statusMetricExpectedResponseHeaderLabelName, secretRedactor.redactHeaderValue(responseHeaderAssertion.name, responseHeaderAssertion.value)),
+	}, statusObserverDescription)
 }
 
-func (las *logzioApiStatus) getResponseTimeGaugeObserver(responseTime float64) *float64GaugeObserver {
-	observerCallback := func(_ context.Context, result metric.Float64ObserverResult) {
-		debugLogger.Println("Running response time observer callback...")
+func (ac *apiCheck) getNoMatchResponseBodyStatusGaugeObserver(responseStatusCode int, responseBodyBytes []byte, responseBodyAssertion *responseBodyAssertion) *int64GaugeObserver {
+	matched, err := responseBodyAssertion.assert(responseBodyBytes)
+	if matched {
+		return nil
+	}
+
+	attributes := []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, responseBodyAssertion.getStatusLabelValue()),
+		attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
+		attribute.String(statusMetricExpectedResponseBodyLabelName, responseBodyAssertion.value),
+	}
+
+	attributes = append(attributes, ac.getResponseBodyLabel(responseBodyBytes)...)
+
+	if err != nil {
+		attributes = append(attributes, ac.getErrorLabel(err)...)
+	}
+
+	return newInt64GaugeObserver(statusMetricName, statusMetricValue, attributes, statusObserverDescription)
+}
 
-		result.Observe(responseTime,
-			attribute.String(urlLabelName, las.url),
-			attribute.String(methodLabelName, las.method),
-			attribute.String(unitLabelName, responseTimeMetricUnitLabelValue))
+// Returns a slow response status of the highest response time threshold the response time reached
+func (ac *apiCheck) getSlowResponseStatusGaugeObserver(responseStatusCode int, responseTime float64) *int64GaugeObserver {
+	severity := ""
+	threshold := time.Duration(0)
+
+	if ac.criticalResponseTimeThreshold != 0 && responseTime >= float64(ac.criticalResponseTimeThreshold)/float64(time.Millisecond) {
+		severity, threshold = criticalSlowResponseSeverityLabelValue, ac.criticalResponseTimeThreshold
+	} else if ac.warningResponseTimeThreshold != 0 && responseTime >= float64(ac.warningResponseTimeThreshold)/float64(time.Millisecond) {
+		severity, threshold = warningSlowResponseSeverityLabelValue, ac.warningResponseTimeThreshold
 	}
 
-	return newFloat64GaugeObserver(responseTimeMetricName, observerCallback, "API response time")
+	if severity == "" {
+		debugLogger.Println("No slow response status")
+		return nil
+	}
+
+	return newInt64GaugeObserver(statusMetricName, statusMetricValue, []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, slowResponseStatusMetricStatusLabelValue),
+		attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
+		attribute.String(statusMetricSlowResponseSeverityLabelName, severity),
+		attribute.Float64(statusMetricResponseTimeThresholdLabelName, float64(threshold)/float64(time.Millisecond)),
+		attribute.String(statusMetricResponseTimeThresholdUnitLabelName, responseTimeMetricUnitLabelValue),
+	}, statusObserverDescription)
 }
 
-func (las *logzioApiStatus) getResponseBodyLengthGaugeObserver(responseBodyLength int) *int64GaugeObserver {
-	observerCallback := func(_ context.Context, result metric.Int64ObserverResult) {
-		debugLogger.Println("Running response body length observer callback...")
+func (ac *apiCheck) getSuccessStatusGaugeObserver(responseStatusCode int) *int64GaugeObserver {
+	return newInt64GaugeObserver(statusMetricName, statusMetricValue, []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, successStatusMetricStatusLabelValue),
+		attribute.Int(statusMetricResponseStatusCodeLabelName, responseStatusCode),
+	}, statusObserverDescription)
+}
+
+func (ac *apiCheck) getResponseTimeGaugeObserver(responseTime float64) *float64GaugeObserver {
+	return newFloat64GaugeObserver(responseTimeMetricName, responseTime, []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(unitLabelName, responseTimeMetricUnitLabelValue),
+	}, "API response time")
+}
+
+// Returns gauge observers of the HTTP request phases that happened
+func (ac *apiCheck) getTimingGaugeObservers(timings *httpTimings) []metricRegister {
+	gaugeObservers := make([]metricRegister, 0)
+	phases := []struct {
+		metricName  string
+		getTime     func() (float64, bool)
+		description string
+	}{
+		{dnsLookupTimeMetricName, timings.dnsLookupTime, "API DNS lookup time"},
+		{connectTimeMetricName, timings.connectTime, "API TCP connect time"},
+		{tlsHandshakeTimeMetricName, timings.tlsHandshakeTime, "API TLS handshake time"},
+		{timeToFirstByteMetricName, timings.timeToFirstByte, "API time to first byte"},
+		{bodyDownloadTimeMetricName, timings.bodyDownloadTime, "API response body download time"},
+	}
 
-		result.Observe(int64(responseBodyLength),
-			attribute.String(urlLabelName, las.url),
-			attribute.String(methodLabelName, las.method),
-			attribute.String(unitLabelName, responseBodyLengthMetricUnitLabelValue))
+	for _, phase := range phases {
+		if phaseTime, ok := phase.getTime(); ok {
+			gaugeObservers = append(gaugeObservers, ac.getTimingGaugeObserver(phase.metricName, phaseTime, phase.description))
+		}
 	}
 
-	return newInt64GaugeObserver(responseBodyLengthMetricName, observerCallback, "API response body length")
+	return gaugeObservers
+}
+
+func (ac *apiCheck) getTimingGaugeObserver(metricName string, phaseTime float64, description string) *float64GaugeObserver {
+	return newFloat64GaugeObserver(metricName, phaseTime, []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(unitLabelName, responseTimeMetricUnitLabelValue),
+	}, description)
+}
+
+func (ac *apiCheck) getResponseBodyLengthGaugeObserver(responseBodyLength int) *int64GaugeObserver {
+	return newInt64GaugeObserver(responseBodyLengthMetricName, int64(responseBodyLength), []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(unitLabelName, responseBodyLengthMetricUnitLabelValue),
+	}, "API response body length")
 }
 
 func getApiRequestHeaders() (map[string]string, error) {
+	headers, err := parseHeaders(os.Getenv(headersEnvName))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range headers {
+		debugLogger.Println("Got API HTTP request header:", key, "=", secretRedactor.redactHeaderValue(key, value))
+	}
+
+	return headers, nil
+}
+
+// Parses headers separated by comma, where each header's key and value are separated by '='
+func parseHeaders(headersString string) (map[string]string, error) {
 	var headers map[string]string
 
-	if headersString := os.Getenv(headersEnvName); headersString != "" {
+	if headersString != "" {
 		headers = make(map[string]string)
 
 		for _, header := range strings.Split(headersString, ",") {
@@ -421,24 +877,50 @@ func getApiRequestHeaders() (map[string]string, error) {
 			header = strings.Replace(header, " ", "", -1)
 			headerKeyAndValue := strings.Split(header, "=")
 			headers[headerKeyAndValue[0]] = headerKeyAndValue[1]
-
-			debugLogger.Println("Got API HTTP request header:", headerKeyAndValue[0], "=", headerKeyAndValue[1])
 		}
 	}
 
 	return headers, nil
 }
 
-func (las *logzioApiStatus) createController() (*controller.Controller, error) {
-	config := metricsExporter.Config{
-		LogzioMetricsListener: las.logzioMetricsListener,
-		LogzioMetricsToken:    las.logzioMetricsToken,
-		RemoteTimeout:         30 * time.Second,
-		PushInterval:          15 * time.Second,
+// Groups observers of the same metric, in the order of their first observer
+func mergeMetricRegisters(metricRegisters []metricRegister) []metricRegister {
+	mergedMetricRegisters := make([]metricRegister, 0, len(metricRegisters))
+	int64GaugeObserverGroups := make(map[string]*int64GaugeObserverGroup)
+	float64GaugeObserverGroups := make(map[string]*float64GaugeObserverGroup)
+
+	for _, metricReg := range metricRegisters {
+		switch gaugeObserver := metricReg.(type) {
+		case *int64GaugeObserver:
+			if group, ok := int64GaugeObserverGroups[gaugeObserver.name]; ok {
+				*group = append(*group, gaugeObserver)
+				continue
+			}
+
+			group := &int64GaugeObserverGroup{gaugeObserver}
+			int64GaugeObserverGroups[gaugeObserver.name] = group
+			mergedMetricRegisters = append(mergedMetricRegisters, group)
+		case *float64GaugeObserver:
+			if group, ok := float64GaugeObserverGroups[gaugeObserver.name]; ok {
+				*group = append(*group, gaugeObserver)
+				continue
+			}
+
+			group := &float64GaugeObserverGroup{gaugeObserver}
+			float64GaugeObserverGroups[gaugeObserver.name] = group
+			mergedMetricRegisters = append(mergedMetricRegisters, group)
+		default:
+			mergedMetricRegisters = append(mergedMetricRegisters, metricReg)
+		}
 	}
 
-	return metricsExporter.InstallNewPipeline(config,
-		controller.WithCollectPeriod(5*time.Second),
+	return mergedMetricRegisters
+}
+
+func (las *logzioApiStatus) createController() (*controller.Controller, error) {
+	exporter := newMultiMetricsExporter(las.metricsExporters)
+	options := []controller.Option{
+		controller.WithCollectPeriod(5 * time.Second),
 		controller.WithResource(
 			resource.NewWithAttributes(
 				semconv.SchemaURL,
@@ -447,7 +929,15 @@ func (las *logzioApiStatus) createController() (*controller.Controller, error) {
 				attribute.String(geoHashLabelName, geoHash),
 			),
 		),
-	)
+	}
+
+	if len(las.metricsExporters) > 0 {
+		options = append(options, controller.WithExporter(exporter))
+	}
+
+	cont := controller.New(processor.NewFactory(simple.NewWithInexpensiveDistribution(), exporter), options...)
+
+	return cont, cont.Start(las.ctx)
 }
 
 func (las *logzioApiStatus) collectMetrics(metricRegisters []metricRegister) error {
@@ -458,61 +948,191 @@ func (las *logzioApiStatus) collectMetrics(metricRegisters []metricRegister) err
 
 	debugLogger.Println("Collecting metrics...")
 
-	defer func() {
-		handleErr(cont.Stop(las.ctx))
-	}()
-
 	meter := cont.Meter(meterName)
 
-	for _, metricReg := range metricRegisters {
+	for _, metricReg := range mergeMetricRegisters(metricRegisters) {
 		metricReg.registerMetric(meter)
 	}
 
+	// Stopping collects the metrics one last time and sends them to the exporters
+	if err = cont.Stop(las.ctx); err != nil {
+		return fmt.Errorf("error sending metrics: %v", err)
+	}
+
 	return nil
 }
 
-func run(ctx context.Context) error {
-	setRegionLocation()
-	gaugeObservers := make([]metricRegister, 0)
-	apiStatus, err := newLogzioApiStatus(ctx)
+// Returns the check's gauge observers, and its response if it got one
+func (ac *apiCheck) getGaugeObservers(ctx context.Context) ([]metricRegister, *checkResponse, error) {
+	attempt, cancel, err := ac.getApiHttpResponseWithRetries(ctx)
 	if err != nil {
-		return fmt.Errorf("error creating logzioApiStatus instance: %v", err)
+		return nil, nil, err
 	}
 
-	request, err := apiStatus.createApiHttpRequest()
-	if err != nil {
-		return fmt.Errorf("error creating API HTTP request: %v", err)
+	defer cancel()
+
+	gaugeObservers := ac.getRetryGaugeObservers(attempt)
+	response, responseTime, timings := attempt.response, attempt.responseTime, attempt.timings
+
+	if statusGaugeObserver := ac.getAuthFailedStatusGaugeObserver(attempt.authErr); statusGaugeObserver != nil {
+		return append(gaugeObservers, statusGaugeObserver), nil, nil
 	}
 
-	response, responseTime, err := apiStatus.getApiHttpResponse(request)
-	if statusGaugeObserver := apiStatus.getResponseErrorStatusGaugeObserver(err); statusGaugeObserver != nil {
-		gaugeObservers = append(gaugeObservers, statusGaugeObserver)
-		return apiStatus.collectMetrics(gaugeObservers)
+	if statusGaugeObserver := ac.getResponseErrorStatusGaugeObserver(attempt.err); statusGaugeObserver != nil {
+		return append(gaugeObservers, statusGaugeObserver), nil, nil
 	}
 
-	responseTimeGaugeObserver := apiStatus.getResponseTimeGaugeObserver(responseTime)
+	responseTimeGaugeObserver := ac.getResponseTimeGaugeObserver(responseTime)
 	gaugeObservers = append(gaugeObservers, responseTimeGaugeObserver)
 
 	defer closeResponseBody(response.Body)
 
-	bodyBytes, err := io.ReadAll(response.Body)
-	if statusGaugeObserver := apiStatus.getReadResponseBodyErrorStatusGaugeObserver(response.StatusCode, err); statusGaugeObserver != nil {
-		gaugeObservers = append(gaugeObservers, statusGaugeObserver)
-		return apiStatus.collectMetrics(gaugeObservers)
+	var bodyBytes []byte
+
+	// Responses to HEAD requests have no body
+	if ac.method != http.MethodHead {
+		bodyBytes, err = io.ReadAll(response.Body)
+		if err == nil {
+			timings.setNow(&timings.bodyDone)
+		}
 	}
 
-	responseBodyLengthGaugeObserver := apiStatus.getResponseBodyLengthGaugeObserver(len(bodyBytes))
-	gaugeObservers = append(gaugeObservers, responseBodyLengthGaugeObserver)
+	capturedResponse := &checkResponse{statusCode: response.StatusCode, header: response.Header, body: bodyBytes}
+	gaugeObservers = append(gaugeObservers, ac.getTimingGaugeObservers(timings)...)
 
-	if statusGaugeObserver := apiStatus.getNoMatchStatusGaugeObserver(response.StatusCode, bodyBytes); statusGaugeObserver != nil {
-		gaugeObservers = append(gaugeObservers, statusGaugeObserver)
-		return apiStatus.collectMetrics(gaugeObservers)
+	if response.TLS != nil {
+		gaugeObservers = append(gaugeObservers, ac.getCertificateGaugeObservers(response.TLS, response.Request.URL.Hostname())...)
 	}
 
-	statusGaugeObserver := apiStatus.getSuccessStatusGaugeObserver(response.StatusCode)
-	gaugeObservers = append(gaugeObservers, statusGaugeObserver)
+	if statusGaugeObserver := ac.getReadResponseBodyErrorStatusGaugeObserver(response.StatusCode, err); statusGaugeObserver != nil {
+		return append(gaugeObservers, statusGaugeObserver), capturedResponse, nil
+	}
+
+	if ac.method != http.MethodHead {
+		responseBodyLengthGaugeObserver := ac.getResponseBodyLengthGaugeObserver(len(bodyBytes))
+		gaugeObservers = append(gaugeObservers, responseBodyLengthGaugeObserver)
+	}
+
+	if statusGaugeObserver := ac.getNoMatchStatusGaugeObserver(response.StatusCode, response.Header, bodyBytes); statusGaugeObserver != nil {
+		return append(gaugeObservers, statusGaugeObserver), capturedResponse, nil
+	}
+
+	// An expiring certificate needs action, so it takes precedence over a slow response
+	if statusGaugeObserver := ac.getCertificateExpiringStatusGaugeObserver(response.StatusCode, response.TLS); statusGaugeObserver != nil {
+		return append(gaugeObservers, statusGaugeObserver), capturedResponse, nil
+	}
 
-	return apiStatus.collectMetrics(gaugeObservers)
+	if statusGaugeObserver := ac.getSlowResponseStatusGaugeObserver(response.StatusCode, responseTime); statusGaugeObserver != nil {
+		return append(gaugeObservers, statusGaugeObserver), capturedResponse, nil
+	}
+
+	statusGaugeObserver := ac.getSuccessStatusGaugeObserver(response.StatusCode)
+
+	return append(gaugeObservers, statusGaugeObserver), capturedResponse, nil
+}
+
+// Runs the check once
+func (ac *apiCheck) run(ctx context.Context) *checkRun {
+	run := &checkRun{start: time.Now()}
+	run.gaugeObservers, run.response, run.err = ac.getGaugeObservers(ctx)
+	run.duration = time.Since(run.start)
+	run.result = ac.newCheckResult(run.gaugeObservers)
+
+	return run
+}
+
+// Runs the checks like a scheduled invocation: ships their logs, updates their states and sends notifications of their
+// status changes. Returns their gauge observers and results in the checks' order.
+func (las *logzioApiStatus) runChecks() ([]metricRegister, []*checkResult, error) {
+	gaugeObservers, checkResults, checksRuns, checksError := las.runChecksOnly()
+
+	if las.logShipper != nil {
+		if err := las.logShipper.shipCheckLogs(las.ctx, las.checks, checkResults, checksRuns); err != nil {
+			errorLogger.Printf("Error shipping checks logs: %v\n", err)
+		}
+	}
+
+	if las.stateStore != nil {
+		stateGaugeObservers, transitions := las.updateChecksStates(las.checks, checkResults, checksRuns)
+		gaugeObservers = append(gaugeObservers, stateGaugeObservers...)
+		las.notifyStatusChanges(las.checks, checkResults, transitions)
+	}
+
+	return gaugeObservers, checkResults, checksError
+}
+
+// Runs the checks in a bounded worker pool and logs their results, without any other side effect.
+// Returns their gauge observers, results and runs in the checks' order.
+func (las *logzioApiStatus) runChecksOnly() ([]metricRegister, []*checkResult, []*checkRun, error) {
+	runStart := time.Now()
+	checksRuns := make([]*checkRun, len(las.checks))
+	checkIndexes := make(chan int)
+	workers := las.checksConcurrency
+
+	if workers > len(las.checks) {
+		workers = len(las.checks)
+	}
+
+	var waitGroup sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for checkIndex := range checkIndexes {
+				check := las.checks[checkIndex]
+				debugLogger.Printf("Running check %s...\n", check.name)
+
+				checksRuns[checkIndex] = check.run(las.ctx)
+			}
+		}()
+	}
+
+	for checkIndex := range las.checks {
+		checkIndexes <- checkIndex
+	}
+
+	close(checkIndexes)
+	waitGroup.Wait()
+
+	gaugeObservers := make([]metricRegister, 0)
+	checkResults := make([]*checkResult, 0, len(las.checks))
+	var checksError error
+
+	for checkIndex, check := range las.checks {
+		checkResults = append(checkResults, checksRuns[checkIndex].result)
+
+		if checksRuns[checkIndex].err != nil {
+			if checksError == nil {
+				checksError = fmt.Errorf("error running check %s: %v", check.name, checksRuns[checkIndex].err)
+			}
+
+			continue
+		}
+
+		gaugeObservers = append(gaugeObservers, checksRuns[checkIndex].gaugeObservers...)
+	}
+
+	logChecksResults(las.checks, checkResults, checksRuns, time.Since(runStart))
+
+	return gaugeObservers, checkResults, checksRuns, checksError
+}
+
+func run(ctx context.Context) error {
+	setRegionLocation()
+	apiStatus, err := newLogzioApiStatus(ctx, lambdaMode)
+	if err != nil {
+		return fmt.Errorf("error creating logzioApiStatus instance: %v", err)
+	}
+
+	gaugeObservers, _, checksErr := apiStatus.runChecks()
+	if err = apiStatus.collectMetrics(gaugeObservers); err != nil {
+		return err
+	}
+
+	return checksErr
 }
 
 func setRegionLocation() {
@@ -529,27 +1149,12 @@ func setRegionLocation() {
 	}
 }
 
-func handleErr(err error) {
-	if err != nil {
-		panic(fmt.Errorf("something went wrong: %v", err))
-	}
-}
-
 func closeResponseBody(responseBody io.ReadCloser) {
 	if err := responseBody.Close(); err != nil {
 		panic(fmt.Errorf("error closing response body: %v", err))
 	}
 }
 
-// Wrapper for first invocation from cloud formation custom resource
-func customResourceRun(ctx context.Context, event cfn.Event) (physicalResourceID string, data map[string]interface{}, err error) {
-	if err = run(ctx); err != nil {
-		errorLogger.Printf("Error in first running: %s", err.Error())
-	}
-
-	return
-}
-
 func HandleRequest(ctx context.Context, event cfn.Event) (string, error) {
 	infoLogger.Println("Starting to get API status...")
 
@@ -558,15 +1163,47 @@ func HandleRequest(ctx context.Context, event cfn.Event) (string, error) {
 		if err := run(ctx); err != nil {
 			return "lambda finished", err
 		}
-	} else {
-		// Custom resource invocation
-		lambda.Start(cfn.LambdaWrap(customResourceRun))
+
+		infoLogger.Println("API status has been sent to Logz.io successfully")
+		return "lambda finished", nil
+	}
+
+	// Custom resource invocation, the wrapper sends the response to CloudFormation
+	if _, err := cfn.LambdaWrap(customResourceRun)(ctx, event); err != nil {
+		return "lambda finished", fmt.Errorf("error sending custom resource response: %v", err)
 	}
 
-	infoLogger.Println("API status has been sent to Logz.io successfully")
 	return "lambda finished", nil
 }
 
 func main() {
-	lambda.Start(HandleRequest)
+	setSecretRedactor()
+
+	if len(os.Args) > 1 && os.Args[1] == checkCommandName {
+		// Logs go to stderr, so the results can be piped
+		if err := setLoggers(os.Stderr); err != nil {
+			errorLogger.Println(err)
+			os.Exit(checkCommandUsageCode)
+		}
+
+		os.Exit(runCheckCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	if err := setLoggers(os.Stdout); err != nil {
+		errorLogger.Fatalln(err)
+	}
+
+	mode := flag.String(modeFlagName, os.Getenv(modeEnvName), fmt.Sprintf("run mode: %s or %s (default %s)", lambdaMode, daemonMode, lambdaMode))
+	flag.Parse()
+
+	switch *mode {
+	case "", lambdaMode:
+		lambda.Start(HandleRequest)
+	case daemonMode:
+		if err := runDaemonUntilSignal(); err != nil {
+			errorLogger.Fatalf("Error running daemon: %v\n", err)
+		}
+	default:
+		errorLogger.Fatalf("Mode must be %s or %s\n", lambdaMode, daemonMode)
+	}
 }
@@ -83,22 +83,29 @@ func TestNewLogzioApiStatus_Success(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	apiStatus, err := newLogzioApiStatus(context.Background())
+	apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
 	require.NoError(t, err)
 	require.NotNil(t, apiStatus)
-
-	assert.Equal(t, "https://example.api:1234", apiStatus.url)
-	assert.Equal(t, http.MethodGet, apiStatus.method)
-	assert.Equal(t, map[string]string{"Content-Type": "text/application", "Accept": "text/application"}, apiStatus.headers)
-	assert.Equal(t, "test", apiStatus.body)
-	assert.Equal(t, 10*time.Second, apiStatus.responseTimeout)
-	assert.Empty(t, apiStatus.bearerToken)
-	assert.Empty(t, apiStatus.username)
-	assert.Empty(t, apiStatus.password)
-	assert.Equal(t, http.StatusOK, apiStatus.expectedResponseStatusCode)
-	assert.Equal(t, "success", apiStatus.expectedResponseBody)
+	require.Len(t, apiStatus.checks, 1)
+
+	check := apiStatus.checks[0]
+
+	assert.Equal(t, defaultCheckName, check.name)
+	assert.Equal(t, "https://example.api:1234", check.url)
+	assert.Equal(t, http.MethodGet, check.method)
+	assert.Equal(t, map[string]string{"Content-Type": "text/application", "Accept": "text/application"}, check.headers)
+	assert.Equal(t, "test", check.body)
+	assert.Equal(t, 10*time.Second, check.responseTimeout)
+	assert.Empty(t, check.bearerToken)
+	assert.Empty(t, check.username)
+	assert.Empty(t, check.password)
+	assert.Equal(t, "200", check.expectedResponseStatusCode.expression)
+	require.Len(t, check.responseBodyAssertions, 1)
+	assert.Equal(t, equalsAssertionType, check.responseBodyAssertions[0].assertionType)
+	assert.Equal(t, "success", check.responseBodyAssertions[0].value)
 	assert.Equal(t, "https://listener.logz.io:8053", apiStatus.logzioMetricsListener)
 	assert.Equal(t, "123456789a", apiStatus.logzioMetricsToken)
+	assert.Equal(t, defaultChecksConcurrency, apiStatus.checksConcurrency)
 
 	os.Clearenv()
 }
@@ -128,7 +135,7 @@ func TestNewLogzioApiStatus_NoApiURL(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -159,7 +166,7 @@ func TestNewLogzioApiStatus_NoMethod(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -193,7 +200,7 @@ func TestNewLogzioApiStatus_BadHeaders(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -224,7 +231,7 @@ func TestNewLogzioApiStatus_NoApiResponseTimeout(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -258,7 +265,7 @@ func TestNewLogzioApiStatus_NoApiResponseTimeoutNumber(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -292,7 +299,7 @@ func TestNewLogzioApiStatus_NoApiResponseTimeoutPositiveNumber(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -323,7 +330,7 @@ func TestNewLogzioApiStatus_NoExpectedStatusCode(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -357,7 +364,7 @@ func TestNewLogzioApiStatus_NoExpectedStatusCodeNumber(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -391,7 +398,7 @@ func TestNewLogzioApiStatus_NoExpectedStatusCodeValidStatusCode(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -422,7 +429,7 @@ func TestNewLogzioApiStatus_NoLogzioMetricsListener(t *testing.T) {
 	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
@@ -453,30 +460,31 @@ func TestNewLogzioApiStatus_NoLogzioMetricsToken(t *testing.T) {
 	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
 	require.NoError(t, err)
 
-	_, err = newLogzioApiStatus(context.Background())
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
 	require.Error(t, err)
 
 	os.Clearenv()
 }
 
 func TestCreateApiHttpRequest_Success(t *testing.T) {
-	apiStatus := &logzioApiStatus{
-		ctx:                        context.Background(),
-		logzioMetricsListener:      "https://listener.logz.io:8053",
-		logzioMetricsToken:         "123456789a",
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
 		url:                        "https://example.api:1234",
 		method:                     http.MethodGet,
 		headers:                    map[string]string{"Content-Type": "text/application", "Accept": "text/application"},
 		body:                       "test",
-		responseTimeout:            10,
+		responseTimeout:            10 * time.Second,
 		bearerToken:                "",
 		username:                   "",
 		password:                   "",
-		expectedResponseStatusCode: http.StatusOK,
-		expectedResponseBody:       "success",
+		expectedResponseStatusCode: expectedResponseStatusCode,
+		responseBodyAssertions:     []*responseBodyAssertion{{assertionType: equalsAssertionType, value: "success"}},
 	}
 
-	request, err := apiStatus.createApiHttpRequest()
+	request, err := check.createApiHttpRequest(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, request)
 
@@ -490,27 +498,28 @@ func TestCreateApiHttpRequest_Success(t *testing.T) {
 	assert.Equal(t, http.MethodGet, request.Method)
 	assert.Equal(t, []string{"text/application"}, request.Header["Content-Type"])
 	assert.Equal(t, []string{"text/application"}, request.Header["Accept"])
-	assert.Equal(t, apiStatus.body, string(bodyBytes))
+	assert.Equal(t, check.body, string(bodyBytes))
 }
 
 func TestGetApiHttpResponse(t *testing.T) {
-	apiStatus := &logzioApiStatus{
-		ctx:                        context.Background(),
-		logzioMetricsListener:      "https://listener.logz.io:8053",
-		logzioMetricsToken:         "123456789a",
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
 		url:                        "https://example.api:1234",
 		method:                     http.MethodGet,
 		headers:                    map[string]string{"Content-Type": "text/application", "Accept": "text/application"},
 		body:                       "test",
-		responseTimeout:            10,
+		responseTimeout:            10 * time.Second,
 		bearerToken:                "",
 		username:                   "",
 		password:                   "",
-		expectedResponseStatusCode: http.StatusOK,
-		expectedResponseBody:       "success",
+		expectedResponseStatusCode: expectedResponseStatusCode,
+		responseBodyAssertions:     []*responseBodyAssertion{{assertionType: equalsAssertionType, value: "success"}},
 	}
 
-	request, err := apiStatus.createApiHttpRequest()
+	request, err := check.createApiHttpRequest(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, request)
 
@@ -520,7 +529,7 @@ func TestGetApiHttpResponse(t *testing.T) {
 	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234",
 		httpmock.NewStringResponder(http.StatusOK, "success"))
 
-	response, responseTime, err := apiStatus.getApiHttpResponse(request)
+	response, responseTime, err := check.getApiHttpResponse(request)
 	require.NoError(t, err)
 	require.NotNil(t, response)
 	require.NotNil(t, responseTime)
@@ -536,22 +545,32 @@ func TestGetApiHttpResponse(t *testing.T) {
 }
 
 func TestCreateController_Success(t *testing.T) {
-	apiStatus := &logzioApiStatus{
-		ctx:                        context.Background(),
-		logzioMetricsListener:      "https://listener.logz.io:8053",
-		logzioMetricsToken:         "123456789a",
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
 		url:                        "https://example.api:1234",
 		method:                     http.MethodGet,
 		headers:                    map[string]string{"Content-Type": "text/application", "Accept": "text/application"},
 		body:                       "test",
-		responseTimeout:            10,
+		responseTimeout:            10 * time.Second,
 		bearerToken:                "",
 		username:                   "",
 		password:                   "",
-		expectedResponseStatusCode: http.StatusOK,
-		expectedResponseBody:       "success",
+		expectedResponseStatusCode: expectedResponseStatusCode,
+		responseBodyAssertions:     []*responseBodyAssertion{{assertionType: equalsAssertionType, value: "success"}},
+	}
+	apiStatus := &logzioApiStatus{
+		ctx:                   context.Background(),
+		logzioMetricsListener: "https://listener.logz.io:8053",
+		logzioMetricsToken:    "123456789a",
+		checks:                []*apiCheck{check},
 	}
 
+	apiStatus.metricsExporters, err = apiStatus.getMetricsExporters()
+	require.NoError(t, err)
+
 	cont, err := apiStatus.createController()
 	require.NoError(t, err)
 	require.NotNil(t, cont)
@@ -564,23 +583,33 @@ func TestCollectMetrics_AllMetrics(t *testing.T) {
 	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
 	require.NoError(t, err)
 
-	apiStatus := &logzioApiStatus{
-		ctx:                        context.Background(),
-		logzioMetricsListener:      "https://listener.logz.io:8053",
-		logzioMetricsToken:         "123456789a",
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
 		url:                        "https://example.api:1234",
 		method:                     http.MethodGet,
 		headers:                    map[string]string{"Content-Type": "text/application", "Accept": "text/application"},
 		body:                       "test",
-		responseTimeout:            10,
+		responseTimeout:            10 * time.Second,
 		bearerToken:                "",
 		username:                   "",
 		password:                   "",
-		expectedResponseStatusCode: http.StatusOK,
-		expectedResponseBody:       "success",
+		expectedResponseStatusCode: expectedResponseStatusCode,
+		responseBodyAssertions:     []*responseBodyAssertion{{assertionType: equalsAssertionType, value: "success"}},
+	}
+	apiStatus := &logzioApiStatus{
+		ctx:                   context.Background(),
+		logzioMetricsListener: "https://listener.logz.io:8053",
+		logzioMetricsToken:    "123456789a",
+		checks:                []*apiCheck{check},
 	}
 
-	request, err := apiStatus.createApiHttpRequest()
+	apiStatus.metricsExporters, err = apiStatus.getMetricsExporters()
+	require.NoError(t, err)
+
+	request, err := check.createApiHttpRequest(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, request)
 
@@ -590,7 +619,7 @@ func TestCollectMetrics_AllMetrics(t *testing.T) {
 	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234",
 		httpmock.NewStringResponder(http.StatusOK, "success"))
 
-	response, responseTime, err := apiStatus.getApiHttpResponse(request)
+	response, responseTime, err := check.getApiHttpResponse(request)
 	require.NoError(t, err)
 	require.NotNil(t, response)
 	require.NotNil(t, responseTime)
@@ -602,9 +631,9 @@ func TestCollectMetrics_AllMetrics(t *testing.T) {
 	defer closeResponseBody(response.Body)
 	setRegionLocation()
 	gaugeObservers := make([]metricRegister, 0)
-	statusGaugeObserver := apiStatus.getSuccessStatusGaugeObserver(http.StatusOK)
-	responseTimeGaugeObserver := apiStatus.getResponseTimeGaugeObserver(responseTime)
-	responseBodyLengthGaugeObserver := apiStatus.getResponseBodyLengthGaugeObserver(len(bodyBytes))
+	statusGaugeObserver := check.getSuccessStatusGaugeObserver(http.StatusOK)
+	responseTimeGaugeObserver := check.getResponseTimeGaugeObserver(responseTime)
+	responseBodyLengthGaugeObserver := check.getResponseBodyLengthGaugeObserver(len(bodyBytes))
 	gaugeObservers = append(gaugeObservers, statusGaugeObserver, responseTimeGaugeObserver, responseBodyLengthGaugeObserver)
 
 	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
@@ -619,22 +648,23 @@ func TestCollectMetrics_AllMetrics(t *testing.T) {
 				assert.Contains(t, []string{statusMetricName, responseTimeMetricName, responseBodyLengthMetricName}, metric["__name__"])
 
 				if metric["__name__"] == statusMetricName {
-					assert.Len(t, metric, 9)
+					assert.Len(t, metric, 10)
 					assert.Equal(t, float64(statusMetricValue), metric["value"])
 					assert.Equal(t, successStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
 					assert.Equal(t, "200", metric[statusMetricResponseStatusCodeLabelName])
 				} else if metric["__name__"] == responseTimeMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.Equal(t, responseTime, metric["value"])
 					assert.Equal(t, responseTimeMetricUnitLabelValue, metric[unitLabelName])
 				} else if metric["__name__"] == responseBodyLengthMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.Equal(t, float64(len(bodyBytes)), metric["value"])
 					assert.Equal(t, responseBodyLengthMetricUnitLabelValue, metric[unitLabelName])
 				}
 
-				assert.Equal(t, apiStatus.url, metric[urlLabelName])
-				assert.Equal(t, apiStatus.method, metric[methodLabelName])
+				assert.Equal(t, check.name, metric[checkNameLabelName])
+				assert.Equal(t, check.url, metric[urlLabelName])
+				assert.Equal(t, check.method, metric[methodLabelName])
 				assert.Equal(t, "us-east-1", metric[awsRegionLabelName])
 				assert.Equal(t, "test", metric[awsLambdaFunctionLabelName])
 				assert.Equal(t, "dq8xb82h008k", metric[geoHashLabelName])
@@ -645,6 +675,7 @@ func TestCollectMetrics_AllMetrics(t *testing.T) {
 
 	err = apiStatus.collectMetrics(gaugeObservers)
 	require.NoError(t, err)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["POST https://listener.logz.io:8053"])
 
 	os.Clearenv()
 }
@@ -701,20 +732,21 @@ func TestRun_SuccessStatus(t *testing.T) {
 				assert.Contains(t, []string{statusMetricName, responseTimeMetricName, responseBodyLengthMetricName}, metric["__name__"])
 
 				if metric["__name__"] == statusMetricName {
-					assert.Len(t, metric, 9)
+					assert.Len(t, metric, 10)
 					assert.Equal(t, float64(statusMetricValue), metric["value"])
 					assert.Equal(t, successStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
 					assert.Equal(t, "200", metric[statusMetricResponseStatusCodeLabelName])
 				} else if metric["__name__"] == responseTimeMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.NotEmpty(t, metric["value"])
 					assert.Equal(t, responseTimeMetricUnitLabelValue, metric[unitLabelName])
 				} else if metric["__name__"] == responseBodyLengthMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.Equal(t, float64(len("success")), metric["value"])
 					assert.Equal(t, responseBodyLengthMetricUnitLabelValue, metric[unitLabelName])
 				}
 
+				assert.Equal(t, defaultCheckName, metric[checkNameLabelName])
 				assert.Equal(t, "https://example.api:1234", metric[urlLabelName])
 				assert.Equal(t, http.MethodGet, metric[methodLabelName])
 				assert.Equal(t, "us-east-1", metric[awsRegionLabelName])
@@ -778,14 +810,16 @@ func TestRun_ConnectionFailedStatus(t *testing.T) {
 
 			metric := metrics[0]
 
-			assert.Len(t, metric, 9)
+			assert.Len(t, metric, 11)
 
 			assert.Equal(t, statusMetricName, metric["__name__"])
 			assert.Equal(t, float64(statusMetricValue), metric["value"])
+			assert.Equal(t, defaultCheckName, metric[checkNameLabelName])
 			assert.Equal(t, "https://example.api:1234", metric[urlLabelName])
 			assert.Equal(t, http.MethodGet, metric[methodLabelName])
 			assert.Equal(t, connectionFailedStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
 			assert.NotEmpty(t, metric[statusMetricErrorLabelName])
+			assert.Equal(t, otherErrorTypeLabelValue, metric[errorTypeLabelName])
 			assert.Equal(t, "us-east-1", metric[awsRegionLabelName])
 			assert.Equal(t, "test", metric[awsLambdaFunctionLabelName])
 			assert.Equal(t, "dq8xb82h008k", metric[geoHashLabelName])
@@ -851,21 +885,22 @@ func TestRun_NoMatchStatusCodeStatus(t *testing.T) {
 				assert.Contains(t, []string{statusMetricName, responseTimeMetricName, responseBodyLengthMetricName}, metric["__name__"])
 
 				if metric["__name__"] == statusMetricName {
-					assert.Len(t, metric, 10)
+					assert.Len(t, metric, 11)
 					assert.Equal(t, float64(statusMetricValue), metric["value"])
 					assert.Equal(t, noMatchStatusCodeStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
 					assert.Equal(t, "401", metric[statusMetricResponseStatusCodeLabelName])
 					assert.Equal(t, "200", metric[statusMetricExpectedResponseStatusCodeLabelName])
 				} else if metric["__name__"] == responseTimeMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.NotEmpty(t, metric["value"])
 					assert.Equal(t, responseTimeMetricUnitLabelValue, metric[unitLabelName])
 				} else if metric["__name__"] == responseBodyLengthMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.Equal(t, float64(len("success")), metric["value"])
 					assert.Equal(t, responseBodyLengthMetricUnitLabelValue, metric[unitLabelName])
 				}
 
+				assert.Equal(t, defaultCheckName, metric[checkNameLabelName])
 				assert.Equal(t, "https://example.api:1234", metric[urlLabelName])
 				assert.Equal(t, http.MethodGet, metric[methodLabelName])
 				assert.Equal(t, "us-east-1", metric[awsRegionLabelName])
@@ -934,22 +969,23 @@ func TestRun_NoMatchResponseBodyStatus(t *testing.T) {
 				assert.Contains(t, []string{statusMetricName, responseTimeMetricName, responseBodyLengthMetricName}, metric["__name__"])
 
 				if metric["__name__"] == statusMetricName {
-					assert.Len(t, metric, 11)
+					assert.Len(t, metric, 12)
 					assert.Equal(t, float64(statusMetricValue), metric["value"])
 					assert.Equal(t, noMatchResponseBodyStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
 					assert.Equal(t, "200", metric[statusMetricResponseStatusCodeLabelName])
 					assert.Equal(t, "success", metric[statusMetricResponseBodyLabelName])
 					assert.Equal(t, "API is working", metric[statusMetricExpectedResponseBodyLabelName])
 				} else if metric["__name__"] == responseTimeMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.NotEmpty(t, metric["value"])
 					assert.Equal(t, responseTimeMetricUnitLabelValue, metric[unitLabelName])
 				} else if metric["__name__"] == responseBodyLengthMetricName {
-					assert.Len(t, metric, 8)
+					assert.Len(t, metric, 9)
 					assert.Equal(t, float64(len("success")), metric["value"])
 					assert.Equal(t, responseBodyLengthMetricUnitLabelValue, metric[unitLabelName])
 				}
 
+				assert.Equal(t, defaultCheckName, metric[checkNameLabelName])
 				assert.Equal(t, "https://example.api:1234", metric[urlLabelName])
 				assert.Equal(t, http.MethodGet, metric[methodLabelName])
 				assert.Equal(t, "us-east-1", metric[awsRegionLabelName])
@@ -965,3 +1001,337 @@ func TestRun_NoMatchResponseBodyStatus(t *testing.T) {
 
 	os.Clearenv()
 }
+
+func TestNewLogzioApiStatus_BadChecksConcurrency(t *testing.T) {
+	err := os.Setenv(apiUrlEnvName, "https://example.api:1234")
+	require.NoError(t, err)
+
+	err = os.Setenv(methodEnvName, http.MethodGet)
+	require.NoError(t, err)
+
+	err = os.Setenv(apiResponseTimeoutEnvName, "10")
+	require.NoError(t, err)
+
+	err = os.Setenv(expectedStatusCodeEnvName, "200")
+	require.NoError(t, err)
+
+	err = os.Setenv(checksConcurrencyEnvName, "0")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.Error(t, err)
+
+	os.Clearenv()
+}
+
+func TestRunChecks_Concurrently(t *testing.T) {
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	checks := make([]*apiCheck, 0)
+
+	for _, name := range []string{"first", "second", "third", "fourth"} {
+		checks = append(checks, &apiCheck{
+			name:                       name,
+			url:                        "https://example.api:1234/" + name,
+			method:                     http.MethodGet,
+			responseTimeout:            10 * time.Second,
+			expectedResponseStatusCode: expectedResponseStatusCode,
+		})
+	}
+
+	apiStatus := &logzioApiStatus{
+		ctx:               context.Background(),
+		checks:            checks,
+		checksConcurrency: 2,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	for _, check := range checks {
+		httpmock.RegisterResponder(http.MethodGet, check.url,
+			httpmock.NewStringResponder(http.StatusOK, "success").Delay(500*time.Millisecond))
+	}
+
+	start := time.Now()
+	gaugeObservers, _, err := apiStatus.runChecks()
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Len(t, gaugeObservers, 12)
+}
+
+func TestRun_ResponseTimeoutStatus(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(apiUrlEnvName, "https://example.api:1234")
+	require.NoError(t, err)
+
+	err = os.Setenv(methodEnvName, http.MethodGet)
+	require.NoError(t, err)
+
+	err = os.Setenv(apiResponseTimeoutEnvName, "1")
+	require.NoError(t, err)
+
+	err = os.Setenv(expectedStatusCodeEnvName, "200")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234",
+		httpmock.NewStringResponder(http.StatusOK, "").Delay(2*time.Second))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			require.NotNil(t, metrics)
+
+			assert.Len(t, metrics, 1)
+
+			metric := metrics[0]
+
+			assert.Equal(t, statusMetricName, metric["__name__"])
+			assert.Equal(t, responseTimeoutStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
+			assert.Equal(t, "1", metric[statusMetricResponseTimeoutLabelName])
+			assert.Equal(t, timeoutErrorTypeLabelValue, metric[errorTypeLabelName])
+			assert.Equal(t, responseTimeoutPhaseLabelValue, metric[statusMetricTimeoutPhaseLabelName])
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	os.Clearenv()
+}
+
+func TestGetSlowResponseStatusGaugeObserver(t *testing.T) {
+	check := &apiCheck{name: "test", url: "https://example.api:1234", method: http.MethodGet}
+	assert.Nil(t, check.getSlowResponseStatusGaugeObserver(http.StatusOK, 10000))
+
+	check.warningResponseTimeThreshold = time.Second
+	check.criticalResponseTimeThreshold = 5 * time.Second
+	assert.Nil(t, check.getSlowResponseStatusGaugeObserver(http.StatusOK, 999))
+	assert.NotNil(t, check.getSlowResponseStatusGaugeObserver(http.StatusOK, 1000))
+	assert.NotNil(t, check.getSlowResponseStatusGaugeObserver(http.StatusOK, 5000))
+}
+
+func TestRun_SlowResponseStatus(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(checksEnvName, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+    slow_response:
+      warning: 50ms
+      critical: 200ms
+`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success").Delay(100*time.Millisecond))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			require.NotNil(t, metrics)
+
+			assert.Len(t, metrics, 3)
+
+			for _, metric := range metrics {
+				if metric["__name__"] == statusMetricName {
+					assert.Equal(t, slowResponseStatusMetricStatusLabelValue, metric[statusMetricStatusLabelName])
+					assert.Equal(t, warningSlowResponseSeverityLabelValue, metric[statusMetricSlowResponseSeverityLabelName])
+					assert.Equal(t, "50", metric[statusMetricResponseTimeThresholdLabelName])
+					assert.Equal(t, responseTimeMetricUnitLabelValue, metric[statusMetricResponseTimeThresholdUnitLabelName])
+				}
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	os.Clearenv()
+}
+
+func TestNewApiCheck_Methods(t *testing.T) {
+	for _, method := range supportedMethods {
+		_, err := newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: method, Timeout: "10s", ExpectedStatusCode: "200"})
+		assert.NoError(t, err, method)
+	}
+
+	for _, method := range []string{"", "get", http.MethodConnect, "PURGE"} {
+		_, err := newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: method, Timeout: "10s", ExpectedStatusCode: "200"})
+		assert.Error(t, err, method)
+	}
+}
+
+func TestNewApiCheck_HeadBodyAssertions(t *testing.T) {
+	emptyExpectedBody := ""
+	check, err := newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: http.MethodHead, Timeout: "10s", ExpectedStatusCode: "200", ExpectedBody: &emptyExpectedBody})
+	require.NoError(t, err)
+	assert.Empty(t, check.responseBodyAssertions)
+
+	expectedBody := "success"
+	_, err = newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: http.MethodHead, Timeout: "10s", ExpectedStatusCode: "200", ExpectedBody: &expectedBody})
+	assert.Error(t, err)
+
+	_, err = newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: http.MethodHead, Timeout: "10s", ExpectedStatusCode: "200",
+		BodyAssertions: []*assertionConfig{{Type: containsAssertionType, Value: "success"}}})
+	assert.Error(t, err)
+}
+
+func TestRun_HeadAndOptionsChecks(t *testing.T) {
+	err := os.Setenv(awsRegionEnvName, "us-east-1")
+	require.NoError(t, err)
+	setRegionLocation()
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "test")
+	require.NoError(t, err)
+
+	err = os.Setenv(checksEnvName, `
+checks:
+  - name: users-head
+    url: https://example.api:1234/users
+    method: HEAD
+  - name: users-preflight
+    url: https://example.api:1234/users
+    method: OPTIONS
+    headers:
+      Origin: https://example.com
+      Access-Control-Request-Method: DELETE
+    expected_status_code: 204
+    header_assertions:
+      - name: Access-Control-Allow-Origin
+        type: equals
+        value: https://example.com
+      - name: Access-Control-Allow-Methods
+        type: contains
+        value: DELETE
+  - name: users-delete
+    url: https://example.api:1234/users/1
+    method: DELETE
+    expected_status_code: 204
+`)
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsListenerEnvName, "https://listener.logz.io:8053")
+	require.NoError(t, err)
+
+	err = os.Setenv(logzioMetricsTokenEnvName, "123456789a")
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodHead, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "not read"))
+
+	httpmock.RegisterResponder(http.MethodOptions, "https://example.api:1234/users",
+		func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, "DELETE", request.Header.Get("Access-Control-Request-Method"))
+
+			response := httpmock.NewStringResponse(http.StatusNoContent, "")
+			response.Header.Set("Access-Control-Allow-Origin", request.Header.Get("Origin"))
+			response.Header.Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+
+			return response, nil
+		})
+
+	httpmock.RegisterResponder(http.MethodDelete, "https://example.api:1234/users/1",
+		httpmock.NewStringResponder(http.StatusNoContent, ""))
+
+	httpmock.RegisterResponder(http.MethodPost, "https://listener.logz.io:8053",
+		func(request *http.Request) (*http.Response, error) {
+			metrics, err := getMetrics(request)
+			require.NoError(t, err)
+			require.NotNil(t, metrics)
+
+			statuses := make(map[string]interface{})
+			bodyLengthChecks := make([]interface{}, 0)
+
+			for _, metric := range metrics {
+				switch metric["__name__"] {
+				case statusMetricName:
+					statuses[metric[checkNameLabelName].(string)] = metric[statusMetricStatusLabelName]
+				case responseBodyLengthMetricName:
+					bodyLengthChecks = append(bodyLengthChecks, metric[checkNameLabelName])
+				}
+			}
+
+			assert.Equal(t, map[string]interface{}{
+				"users-head":      successStatusMetricStatusLabelValue,
+				"users-preflight": successStatusMetricStatusLabelValue,
+				"users-delete":    successStatusMetricStatusLabelValue,
+			}, statuses)
+			assert.ElementsMatch(t, []interface{}{"users-preflight", "users-delete"}, bodyLengthChecks)
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	err = run(context.Background())
+	require.NoError(t, err)
+
+	os.Clearenv()
+}
+
+func TestRunChecks_CheckError(t *testing.T) {
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	apiStatus := &logzioApiStatus{
+		ctx: context.Background(),
+		checks: []*apiCheck{
+			{name: "users", url: "https://example.api:1234/users", method: http.MethodGet, responseTimeout: 5 * time.Second, expectedResponseStatusCode: expectedResponseStatusCode},
+			{name: "invalid", url: "https://example.api:1234/orders", method: "BAD METHOD", responseTimeout: 5 * time.Second, expectedResponseStatusCode: expectedResponseStatusCode},
+		},
+		checksConcurrency: 2,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users", httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	// The check that failed to run has the error status, and the other check's result is kept
+	_, checkResults, err := apiStatus.runChecks()
+	require.Error(t, err)
+	require.Len(t, checkResults, 2)
+	assert.Equal(t, successStatusMetricStatusLabelValue, checkResults[0].Status)
+	assert.Equal(t, errorCheckResultStatus, checkResults[1].Status)
+}
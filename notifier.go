@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	notifiersEnvName           = "NOTIFIERS"
+	slackNotifierPreset        = "slack"
+	teamsNotifierPreset        = "teams"
+	pagerDutyNotifierPreset    = "pagerduty"
+	failureNotificationEvent   = "failure"
+	recoveryNotificationEvent  = "recovery"
+	defaultNotifierMethod      = http.MethodPost
+	defaultPagerDutyEventsURL  = "https://events.pagerduty.com/v2/enqueue"
+	notifierAttempts           = 3
+	notifierBackoff            = time.Second
+	notifierTimeout            = 10 * time.Second
+	notificationDedupKeyPrefix = "api-status/"
+)
+
+var notificationEvents = []string{failureNotificationEvent, recoveryNotificationEvent}
+
+// A webhook payload preset, where url is the preset's default URL
+type notifierPreset struct {
+	url     string
+	payload string
+}
+
+var notifierPresets = map[string]*notifierPreset{
+	slackNotifierPreset: {
+		payload: `{"text": {{json .Summary}}}`,
+	},
+	// An Adaptive Card message, as accepted by Teams workflows and incoming webhooks
+	teamsNotifierPreset: {
+		payload: `{"type": "message", "attachments": [{"contentType": "application/vnd.microsoft.card.adaptive", "content": {` +
+			`"$schema": "http://adaptivecards.io/schemas/adaptive-card.json", "type": "AdaptiveCard", "version": "1.4", "body": [` +
+			`{"type": "TextBlock", "size": "Medium", "weight": "Bolder", "wrap": true, "color": {{if eq .Event "failure"}}"Attention"{{else}}"Good"{{end}}, "text": {{json .Summary}}}, ` +
+			`{"type": "FactSet", "facts": [{"title": "Status", "value": {{json .Status}}}, {"title": "Previous status", "value": {{json .PreviousStatus}}}, ` +
+			`{"title": "URL", "value": {{json .URL}}}, {"title": "Consecutive failures", "value": "{{.ConsecutiveFailures}}"}]}]}}]}`,
+	},
+	// Failures trigger an incident that the check's recovery resolves, by the check's dedup key
+	pagerDutyNotifierPreset: {
+		url: defaultPagerDutyEventsURL,
+		payload: `{"routing_key": {{json .RoutingKey}}, "event_action": {{if eq .Event "failure"}}"trigger"{{else}}"resolve"{{end}}, ` +
+			`"dedup_key": {{json .DedupKey}}, "payload": {"summary": {{json .Summary}}, "source": {{json .URL}}, "severity": "error", ` +
+			`"component": {{json .CheckName}}, "custom_details": {{json .}}}}`,
+	},
+}
+
+// The template functions of payloads, where json encodes a value (for example: a string with its quotes)
+var notificationTemplateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		valueBytes, err := json.Marshal(value)
+		return string(valueBytes), err
+	},
+}
+
+type notifierConfig struct {
+	Name    string            `yaml:"name"`
+	Preset  string            `yaml:"preset"`
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Payload string            `yaml:"payload"`
+	// The PagerDuty integration key of the pagerduty preset
+	RoutingKey string   `yaml:"routing_key"`
+	Events     []string `yaml:"events"`
+}
+
+// Sends a request with a payload from its template when a check's status changes
+type webhookNotifier struct {
+	name       string
+	url        string
+	method     string
+	headers    map[string]string
+	payload    *template.Template
+	routingKey string
+	events     map[string]bool
+	client     *http.Client
+	attempts   int
+	backoff    time.Duration
+}
+
+// The data of payload templates, and the default payload
+type statusNotification struct {
+	Event          string            `json:"event"`
+	Summary        string            `json:"summary"`
+	CheckName      string            `json:"check_name"`
+	URL            string            `json:"url"`
+	Method         string            `json:"method"`
+	Status         string            `json:"status"`
+	PreviousStatus string            `json:"previous_status,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	// Failed runs in a row, for recovery notifications the ones before the recovery
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Time                time.Time `json:"time"`
+	DedupKey            string    `json:"dedup_key"`
+	AwsRegion           string    `json:"aws_region,omitempty"`
+	AwsLambdaFunction   string    `json:"aws_lambda_function,omitempty"`
+	RoutingKey          string    `json:"-"`
+}
+
+// Returns the notifiers from NOTIFIERS (a YAML/JSON list), or none if it is not set
+func getWebhookNotifiers() ([]*webhookNotifier, error) {
+	notifiersString := os.Getenv(notifiersEnvName)
+	if notifiersString == "" {
+		return nil, nil
+	}
+
+	configs := make([]*notifierConfig, 0)
+	if err := yaml.Unmarshal([]byte(notifiersString), &configs); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", notifiersEnvName, err)
+	}
+
+	notifiers := make([]*webhookNotifier, 0, len(configs))
+
+	for index, config := range configs {
+		if config == nil {
+			return nil, fmt.Errorf("notifier %d must not be empty", index)
+		}
+
+		notifier, err := newWebhookNotifier(config)
+		if err != nil {
+			return nil, fmt.Errorf("error in notifier %d (%s): %v", index, config.Name, err)
+		}
+
+		notifiers = append(notifiers, notifier)
+	}
+
+	return notifiers, nil
+}
+
+func newWebhookNotifier(config *notifierConfig) (*webhookNotifier, error) {
+	notifier := &webhookNotifier{
+		name:       config.Name,
+		url:        config.URL,
+		method:     strings.ToUpper(config.Method),
+		headers:    map[string]string{"Content-Type": "application/json"},
+		routingKey: config.RoutingKey,
+		events:     make(map[string]bool),
+		client:     &http.Client{Timeout: notifierTimeout},
+		attempts:   notifierAttempts,
+		backoff:    notifierBackoff,
+	}
+
+	// The default payload is the notification itself
+	payload := `{{json .}}`
+
+	if config.Preset != "" {
+		preset, ok := notifierPresets[config.Preset]
+		if !ok {
+			return nil, fmt.Errorf("preset %s must be one of %s, %s or %s", config.Preset, slackNotifierPreset, teamsNotifierPreset, pagerDutyNotifierPreset)
+		}
+
+		payload = preset.payload
+		if notifier.url == "" {
+			notifier.url = preset.url
+		}
+
+		if notifier.name == "" {
+			notifier.name = config.Preset
+		}
+	}
+
+	if config.Preset == pagerDutyNotifierPreset && notifier.routingKey == "" {
+		return nil, fmt.Errorf("routing_key must not be empty for the %s preset", pagerDutyNotifierPreset)
+	}
+
+	if config.Payload != "" {
+		payload = config.Payload
+	}
+
+	if notifier.url == "" {
+		return nil, fmt.Errorf("url must not be empty")
+	}
+
+	if _, err := url.ParseRequestURI(notifier.url); err != nil {
+		return nil, fmt.Errorf("url is invalid")
+	}
+
+	if notifier.method == "" {
+		notifier.method = defaultNotifierMethod
+	}
+
+	if !isSupportedMethod(notifier.method) {
+		return nil, fmt.Errorf("method %s is not supported", notifier.method)
+	}
+
+	for name, value := range config.Headers {
+		notifier.headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	payloadTemplate, err := template.New("payload").Funcs(notificationTemplateFuncs).Parse(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing payload template: %v", err)
+	}
+
+	notifier.payload = payloadTemplate
+
+	events := config.Events
+	if len(events) == 0 {
+		events = notificationEvents
+	}
+
+	for _, event := range events {
+		if event != failureNotificationEvent && event != recoveryNotificationEvent {
+			return nil, fmt.Errorf("event %s must be %s or %s", event, failureNotificationEvent, recoveryNotificationEvent)
+		}
+
+		notifier.events[event] = true
+	}
+
+	if notifier.name == "" {
+		notifier.name = "webhook"
+	}
+
+	return notifier, nil
+}
+
+// Returns the notification event of the transition, or an empty event if it is not a transition between success and failure.
+// A check's first run is a transition from success.
+func getNotificationEvent(transition *checkStateTransition) string {
+	failed := transition.current.Status != successStatusMetricStatusLabelValue
+	previouslyFailed := transition.previous != nil && transition.previous.Status != successStatusMetricStatusLabelValue
+
+	switch {
+	case failed && !previouslyFailed:
+		return failureNotificationEvent
+	case !failed && previouslyFailed:
+		return recoveryNotificationEvent
+	default:
+		return ""
+	}
+}
+
+func newStatusNotification(event string, result *checkResult, transition *checkStateTransition) *statusNotification {
+	notification := &statusNotification{
+		Event:               event,
+		CheckName:           result.Name,
+		URL:                 result.URL,
+		Method:              result.Method,
+		Status:              result.Status,
+		Labels:              result.Labels,
+		ConsecutiveFailures: transition.current.ConsecutiveFailures,
+		Time:                transition.current.UpdatedAt,
+		DedupKey:            notificationDedupKeyPrefix + result.Name,
+		AwsRegion:           awsRegion,
+		AwsLambdaFunction:   os.Getenv(awsLambdaFunctionNameEnvName),
+	}
+
+	if transition.previous != nil {
+		notification.PreviousStatus = transition.previous.Status
+	}
+
+	if event == recoveryNotificationEvent {
+		notification.ConsecutiveFailures = transition.previous.ConsecutiveFailures
+		notification.Summary = fmt.Sprintf("Check %s recovered after %d failed runs (%s %s)", result.Name, notification.ConsecutiveFailures, result.Method, result.URL)
+	} else {
+		notification.Summary = fmt.Sprintf("Check %s failed with status %s (%s %s)", result.Name, result.Status, result.Method, result.URL)
+	}
+
+	return notification
+}
+
+// Notifies the notifiers of the checks whose status changed between success and failure. Failing to notify is logged.
+func (las *logzioApiStatus) notifyStatusChanges(checks []*apiCheck, checkResults []*checkResult, transitions []*checkStateTransition) {
+	for index, transition := range transitions {
+		if transition == nil {
+			continue
+		}
+
+		event := getNotificationEvent(transition)
+		if event == "" {
+			continue
+		}
+
+		notification := newStatusNotification(event, checkResults[index], transition)
+
+		for _, notifier := range las.notifiers {
+			if !notifier.events[event] {
+				continue
+			}
+
+			debugLogger.Printf("Sending check %s %s notification to %s...\n", checks[index].name, event, notifier.name)
+
+			if err := notifier.notify(las.ctx, notification); err != nil {
+				errorLogger.Printf("Error notifying %s of check %s %s: %v\n", notifier.name, checks[index].name, event, err)
+			}
+		}
+	}
+}
+
+func (wn *webhookNotifier) notify(ctx context.Context, notification *statusNotification) error {
+	data := *notification
+	data.RoutingKey = wn.routingKey
+
+	var payload bytes.Buffer
+	if err := wn.payload.Execute(&payload, &data); err != nil {
+		return fmt.Errorf("error executing payload template: %v", err)
+	}
+
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		request, err := http.NewRequestWithContext(ctx, wn.method, wn.url, bytes.NewReader(payload.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+
+		for name, value := range wn.headers {
+			request.Header.Set(name, value)
+		}
+
+		return request, nil
+	}
+
+	// Webhook URLs are secrets (for example: of Slack), so they are removed from request errors
+	if err := sendWithRetries(ctx, wn.client, newRequest, wn.attempts, wn.backoff, "notification"); err != nil {
+		return errors.New(strings.ReplaceAll(err.Error(), wn.url, "<webhook url>"))
+	}
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunChecks_Notifications(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(notifiersEnvName, `
+- url: https://hooks.example.com/api-status
+  headers:
+    Authorization: Bearer secret
+- preset: slack
+  url: https://hooks.slack.com/services/T000/B000/XXXX
+  events: [failure]
+`)
+	require.NoError(t, err)
+
+	apiStatus, err := newCheckCommandApiStatus("")
+	require.NoError(t, err)
+
+	apiStatus.notifiers, err = getWebhookNotifiers()
+	require.NoError(t, err)
+	os.Clearenv()
+
+	apiStatus.stateStore = newMemoryStateStore()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	webhookNotifications := make([]map[string]interface{}, 0)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://hooks.example.com/api-status",
+		func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, "Bearer secret", request.Header.Get("Authorization"))
+			assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+
+			notification := make(map[string]interface{})
+			require.NoError(t, json.NewDecoder(request.Body).Decode(&notification))
+			webhookNotifications = append(webhookNotifications, notification)
+
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	slackMessages := make([]map[string]interface{}, 0)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://hooks.slack.com/services/T000/B000/XXXX",
+		func(request *http.Request) (*http.Response, error) {
+			message := make(map[string]interface{})
+			require.NoError(t, json.NewDecoder(request.Body).Decode(&message))
+			slackMessages = append(slackMessages, message)
+
+			return httpmock.NewStringResponse(http.StatusOK, "ok"), nil
+		})
+
+	for _, statusCode := range []int{http.StatusOK, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK} {
+		httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+			httpmock.NewStringResponder(statusCode, ""))
+
+		_, _, err = apiStatus.runChecks()
+		require.NoError(t, err)
+	}
+
+	require.Len(t, webhookNotifications, 2)
+
+	failure := webhookNotifications[0]
+	assert.Equal(t, failureNotificationEvent, failure["event"])
+	assert.Equal(t, "users", failure["check_name"])
+	assert.Equal(t, noMatchStatusCodeStatusMetricStatusLabelValue, failure["status"])
+	assert.Equal(t, successStatusMetricStatusLabelValue, failure["previous_status"])
+	assert.Equal(t, float64(1), failure["consecutive_failures"])
+	assert.Equal(t, "api-status/users", failure["dedup_key"])
+	assert.Equal(t, "500", failure["labels"].(map[string]interface{})[statusMetricResponseStatusCodeLabelName])
+	assert.Equal(t, "Check users failed with status no_match_status_code (GET https://example.api:1234/users)", failure["summary"])
+
+	recovery := webhookNotifications[1]
+	assert.Equal(t, recoveryNotificationEvent, recovery["event"])
+	assert.Equal(t, successStatusMetricStatusLabelValue, recovery["status"])
+	assert.Equal(t, float64(2), recovery["consecutive_failures"])
+	assert.Equal(t, "Check users recovered after 2 failed runs (GET https://example.api:1234/users)", recovery["summary"])
+
+	assert.Equal(t, []map[string]interface{}{{"text": "Check users failed with status no_match_status_code (GET https://example.api:1234/users)"}}, slackMessages)
+}
+
+func TestRunChecks_NotificationsAcrossColdStarts(t *testing.T) {
+	_, server := newTestS3Server(t)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The fake S3 bucket is a real server
+	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip)
+
+	httpmock.RegisterResponder(http.MethodPost, "https://hooks.example.com/api-status",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+
+	defer func() {
+		processStateStore = newMemoryStateStore()
+	}()
+
+	// Each invocation is a cold start: a new instance of the function, which has a new process
+	invoke := func(storeType string, statusCode int) {
+		processStateStore = newMemoryStateStore()
+		setTestS3StateStoreEnv(t, server.URL)
+
+		for name, value := range map[string]string{
+			checksEnvName:                `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`,
+			metricsExportersEnvName:      stdoutMetricsExporterName,
+			notifiersEnvName:             `[{"url": "https://hooks.example.com/api-status"}]`,
+			stateStoreEnvName:            storeType,
+			awsLambdaFunctionNameEnvName: "api-status",
+		} {
+			err := os.Setenv(name, value)
+			require.NoError(t, err)
+		}
+
+		apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
+		require.NoError(t, err)
+
+		httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+			httpmock.NewStringResponder(statusCode, ""))
+
+		_, _, err = apiStatus.runChecks()
+		require.NoError(t, err)
+
+		os.Clearenv()
+	}
+
+	// With a persistent store, a check that keeps failing is notified once, and its recovery is notified
+	for _, statusCode := range []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK} {
+		invoke(s3StateStoreType, statusCode)
+	}
+
+	assert.Equal(t, 2, httpmock.GetCallCountInfo()["POST https://hooks.example.com/api-status"])
+
+	// A store that is lost on cold starts would notify the same failure after each cold start, so it sends none
+	httpmock.ZeroCallCounters()
+
+	for _, statusCode := range []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK} {
+		invoke(memoryStateStoreType, statusCode)
+	}
+
+	assert.Equal(t, 0, httpmock.GetCallCountInfo()["POST https://hooks.example.com/api-status"])
+}
+
+func TestNotifierPresets(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var payload []byte
+
+	httpmock.RegisterNoResponder(func(request *http.Request) (*http.Response, error) {
+		var err error
+		payload, err = io.ReadAll(request.Body)
+		require.NoError(t, err)
+
+		return httpmock.NewStringResponse(http.StatusAccepted, ""), nil
+	})
+
+	notification := &statusNotification{
+		Event:          failureNotificationEvent,
+		Summary:        `Check users failed with status no_match_response_body (GET https://example.api:1234/users?q="a")`,
+		CheckName:      "users",
+		URL:            `https://example.api:1234/users?q="a"`,
+		Method:         http.MethodGet,
+		Status:         noMatchResponseBodyStatusMetricStatusLabelValue,
+		PreviousStatus: successStatusMetricStatusLabelValue,
+		Labels:         map[string]string{statusMetricResponseBodyLabelName: `{"error": "failure"}`},
+		Time:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DedupKey:       "api-status/users",
+	}
+
+	for _, config := range []*notifierConfig{
+		{Preset: slackNotifierPreset, URL: "https://hooks.slack.com/services/T000/B000/XXXX"},
+		{Preset: teamsNotifierPreset, URL: "https://example.webhook.office.com/webhookb2/XXXX"},
+		{Preset: pagerDutyNotifierPreset, RoutingKey: "R0UT1NGKEY"},
+	} {
+		notifier, err := newWebhookNotifier(config)
+		require.NoError(t, err)
+
+		for _, event := range notificationEvents {
+			notification.Event = event
+
+			err = notifier.notify(context.Background(), notification)
+			require.NoError(t, err)
+
+			message := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal(payload, &message), string(payload))
+
+			switch config.Preset {
+			case slackNotifierPreset:
+				assert.Equal(t, notification.Summary, message["text"])
+			case teamsNotifierPreset:
+				assert.Equal(t, "message", message["type"])
+				assert.Contains(t, string(payload), map[string]string{failureNotificationEvent: `"color": "Attention"`, recoveryNotificationEvent: `"color": "Good"`}[event])
+				assert.Contains(t, string(payload), `"Check users failed with status no_match_response_body (GET https://example.api:1234/users?q=\"a\")"`)
+			case pagerDutyNotifierPreset:
+				assert.Equal(t, "R0UT1NGKEY", message["routing_key"])
+				assert.Equal(t, "api-status/users", message["dedup_key"])
+				assert.Equal(t, map[string]string{failureNotificationEvent: "trigger", recoveryNotificationEvent: "resolve"}[event], message["event_action"])
+				assert.Equal(t, notification.Summary, message["payload"].(map[string]interface{})["summary"])
+				assert.NotContains(t, message["payload"].(map[string]interface{})["custom_details"], "routing_key")
+			}
+		}
+	}
+}
+
+func TestWebhookNotifier_Errors(t *testing.T) {
+	notifier, err := newWebhookNotifier(&notifierConfig{
+		URL:     "https://hooks.slack.com/services/T000/B000/XXXX",
+		Method:  "put",
+		Payload: `{"check": {{json .CheckName}}, "value": {{.Missing}}}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPut, notifier.method)
+	assert.Equal(t, "webhook", notifier.name)
+
+	err = notifier.notify(context.Background(), &statusNotification{CheckName: "users"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error executing payload template")
+
+	notifier, err = newWebhookNotifier(&notifierConfig{Name: "alerts", URL: "https://hooks.slack.com/services/T000/B000/XXXX"})
+	require.NoError(t, err)
+	notifier.backoff = time.Millisecond
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodPost, "https://hooks.slack.com/services/T000/B000/XXXX",
+		httpmock.NewStringResponder(http.StatusBadGateway, "unavailable"))
+
+	err = notifier.notify(context.Background(), &statusNotification{CheckName: "users"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "attempt 3 of 3")
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+
+	httpmock.Reset()
+	httpmock.ZeroCallCounters()
+
+	// The webhook URL is not in the error of a failed request
+	err = notifier.notify(context.Background(), &statusNotification{CheckName: "users"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "XXXX")
+	assert.Contains(t, err.Error(), "<webhook url>")
+}
+
+func TestNewWebhookNotifier_BadConfig(t *testing.T) {
+	for _, config := range []*notifierConfig{
+		{},
+		{URL: "hooks.example.com"},
+		{Preset: "opsgenie", URL: "https://hooks.example.com"},
+		{Preset: pagerDutyNotifierPreset},
+		{URL: "https://hooks.example.com", Method: "CONNECT"},
+		{URL: "https://hooks.example.com", Payload: "{{.CheckName"},
+		{URL: "https://hooks.example.com", Events: []string{"warning"}},
+	} {
+		_, err := newWebhookNotifier(config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestGetNotificationEvent(t *testing.T) {
+	success := &checkState{Status: successStatusMetricStatusLabelValue}
+	timeout := &checkState{Status: responseTimeoutStatusMetricStatusLabelValue, ConsecutiveFailures: 1}
+	noMatch := &checkState{Status: noMatchStatusCodeStatusMetricStatusLabelValue, ConsecutiveFailures: 2}
+
+	for _, test := range []struct {
+		transition    *checkStateTransition
+		expectedEvent string
+	}{
+		{&checkStateTransition{current: success}, ""},
+		{&checkStateTransition{current: timeout}, failureNotificationEvent},
+		{&checkStateTransition{previous: success, current: success}, ""},
+		{&checkStateTransition{previous: success, current: timeout}, failureNotificationEvent},
+		{&checkStateTransition{previous: timeout, current: noMatch}, ""},
+		{&checkStateTransition{previous: noMatch, current: success}, recoveryNotificationEvent},
+	} {
+		assert.Equal(t, test.expectedEvent, getNotificationEvent(test.transition), test.transition)
+	}
+}
+
+func TestGetWebhookNotifiers(t *testing.T) {
+	notifiers, err := getWebhookNotifiers()
+	require.NoError(t, err)
+	assert.Empty(t, notifiers)
+
+	err = os.Setenv(notifiersEnvName, `[{"preset": "pagerduty", "routing_key": "R0UT1NGKEY"}, {"name": "teams", "preset": "teams", "url": "https://example.webhook.office.com/webhookb2/XXXX", "events": ["recovery"]}]`)
+	require.NoError(t, err)
+
+	notifiers, err = getWebhookNotifiers()
+	require.NoError(t, err)
+	require.Len(t, notifiers, 2)
+	assert.Equal(t, pagerDutyNotifierPreset, notifiers[0].name)
+	assert.Equal(t, defaultPagerDutyEventsURL, notifiers[0].url)
+	assert.Equal(t, map[string]bool{failureNotificationEvent: true, recoveryNotificationEvent: true}, notifiers[0].events)
+	assert.Equal(t, map[string]bool{recoveryNotificationEvent: true}, notifiers[1].events)
+
+	for _, notifiersString := range []string{`{"url": "https://hooks.example.com"}`, `[null]`, `[{"preset": "slack"}]`} {
+		err = os.Setenv(notifiersEnvName, notifiersString)
+		require.NoError(t, err)
+
+		_, err = getWebhookNotifiers()
+		assert.Error(t, err, notifiersString)
+	}
+
+	os.Clearenv()
+}
+
+func TestNewLogzioApiStatus_NotifiersWithoutStateStore(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(metricsExportersEnvName, stdoutMetricsExporterName)
+	require.NoError(t, err)
+
+	err = os.Setenv(notifiersEnvName, `[{"preset": "slack", "url": "https://hooks.slack.com/services/T000/B000/XXXX"}]`)
+	require.NoError(t, err)
+
+	_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), stateStoreEnvName)
+
+	err = os.Setenv(stateStoreEnvName, memoryStateStoreType)
+	require.NoError(t, err)
+
+	apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+	assert.Len(t, apiStatus.notifiers, 1)
+
+	// A Lambda function loses the memory store on cold starts, so it does not notify
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "api-status")
+	require.NoError(t, err)
+
+	apiStatus, err = newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+	assert.Empty(t, apiStatus.notifiers)
+
+	os.Clearenv()
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	oauth2TokenURLEnvName                  = "OAUTH2_TOKEN_URL"
+	oauth2ClientIDEnvName                  = "OAUTH2_CLIENT_ID"
+	oauth2ClientSecretEnvName              = "OAUTH2_CLIENT_SECRET"
+	oauth2ScopesEnvName                    = "OAUTH2_SCOPES"
+	oauth2AudienceEnvName                  = "OAUTH2_AUDIENCE"
+	oauth2AuthStyleEnvName                 = "OAUTH2_AUTH_STYLE"
+	headerOAuth2AuthStyle                  = "header"
+	bodyOAuth2AuthStyle                    = "body"
+	authFailedStatusMetricStatusLabelValue = "auth_failed"
+	// Tokens are fetched again this long before they expire, so they don't expire during a request
+	oauth2TokenExpiryMargin         = 30 * time.Second
+	maxOAuth2TokenResponseBodySize  = 64 * 1024
+	maxOAuth2ErrorResponseBodyLabel = 256
+)
+
+// The client credentials grant of a check's requests, where auth_style is how the client ID and secret are sent
+type oauth2Config struct {
+	TokenURL     string   `yaml:"token_url"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
+	Audience     string   `yaml:"audience"`
+	AuthStyle    string   `yaml:"auth_style"`
+}
+
+// Gets tokens from the token endpoint, and keeps the last token until it is about to expire
+type oauth2TokenSource struct {
+	config oauth2Config
+	lock   sync.Mutex
+	token  *oauth2Token
+}
+
+type oauth2Token struct {
+	accessToken string
+	// Zero if the token endpoint did not set expires_in, so the token is not reused
+	expiry time.Time
+}
+
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// A number, or a string of a number as some token endpoints send it
+	ExpiresIn json.RawMessage `json:"expires_in"`
+}
+
+// An error getting the credentials of the check's request, which is the check's auth_failed status
+type authError struct {
+	err error
+}
+
+// The client of token requests, which uses the default transport and its TLS verification. The check's transport is not
+// used, so the client secret is never sent with the check's client certificate or over its insecure_skip_verify connection.
+var oauth2TokenClient = &http.Client{}
+
+// The token sources by their config, kept for the lifetime of the process (which includes warm invocations of a Lambda
+// function), so checks with the same config share their tokens
+var (
+	oauth2TokenSourcesLock sync.Mutex
+	oauth2TokenSources     = make(map[string]*oauth2TokenSource)
+)
+
+func (ae *authError) Error() string {
+	return ae.err.Error()
+}
+
+func (ae *authError) Unwrap() error {
+	return ae.err
+}
+
+// Returns the process's token source of the config, creating it if there is none
+func getOAuth2TokenSource(config *oauth2Config) (*oauth2TokenSource, error) {
+	if config.TokenURL == "" {
+		return nil, fmt.Errorf("token url must not be empty")
+	}
+
+	if _, err := url.ParseRequestURI(config.TokenURL); err != nil {
+		return nil, fmt.Errorf("token url is invalid")
+	}
+
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("client id must not be empty")
+	}
+
+	sourceConfig := *config
+	if sourceConfig.AuthStyle == "" {
+		sourceConfig.AuthStyle = headerOAuth2AuthStyle
+	}
+
+	if sourceConfig.AuthStyle != headerOAuth2AuthStyle && sourceConfig.AuthStyle != bodyOAuth2AuthStyle {
+		return nil, fmt.Errorf("auth style %s must be %s or %s", sourceConfig.AuthStyle, headerOAuth2AuthStyle, bodyOAuth2AuthStyle)
+	}
+
+	keyBytes, err := json.Marshal(sourceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	oauth2TokenSourcesLock.Lock()
+	defer oauth2TokenSourcesLock.Unlock()
+
+	source, ok := oauth2TokenSources[string(keyBytes)]
+	if !ok {
+		source = &oauth2TokenSource{config: sourceConfig}
+		oauth2TokenSources[string(keyBytes)] = source
+	}
+
+	return source, nil
+}
+
+// Returns the kept token, or a new token from the token endpoint if it is about to expire.
+// Only one token is requested at a time, so checks that share the token source don't request it in parallel.
+func (ots *oauth2TokenSource) getToken(ctx context.Context) (string, error) {
+	ots.lock.Lock()
+	defer ots.lock.Unlock()
+
+	if ots.token != nil && time.Now().Add(oauth2TokenExpiryMargin).Before(ots.token.expiry) {
+		return ots.token.accessToken, nil
+	}
+
+	debugLogger.Printf("Requesting OAuth2 token from %s...\n", secretRedactor.redactURL(ots.config.TokenURL))
+
+	token, err := ots.requestToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ots.token = token
+
+	return token.accessToken, nil
+}
+
+// Drops the kept token (for example: if the API rejected it), so the next request gets a new token
+func (ots *oauth2TokenSource) invalidateToken() {
+	ots.lock.Lock()
+	defer ots.lock.Unlock()
+
+	ots.token = nil
+}
+
+func (ots *oauth2TokenSource) requestToken(ctx context.Context) (*oauth2Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	if len(ots.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(ots.config.Scopes, " "))
+	}
+
+	if ots.config.Audience != "" {
+		form.Set("audience", ots.config.Audience)
+	}
+
+	if ots.config.AuthStyle == bodyOAuth2AuthStyle {
+		form.Set("client_id", ots.config.ClientID)
+		form.Set("client_secret", ots.config.ClientSecret)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ots.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating token request: %v", err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	// The client ID and secret are form encoded before they are sent in the header (RFC 6749, section 2.3.1)
+	if ots.config.AuthStyle == headerOAuth2AuthStyle {
+		request.SetBasicAuth(url.QueryEscape(ots.config.ClientID), url.QueryEscape(ots.config.ClientSecret))
+	}
+
+	start := time.Now()
+
+	response, err := oauth2TokenClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting OAuth2 token: %w", err)
+	}
+
+	defer closeResponseBody(response.Body)
+
+	bodyBytes, err := io.ReadAll(io.LimitReader(response.Body, maxOAuth2TokenResponseBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("error reading OAuth2 token response: %w", err)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := truncateString(string(bodyBytes), maxOAuth2ErrorResponseBodyLabel)
+		return nil, fmt.Errorf("token endpoint returned status code %d: %s", response.StatusCode, body)
+	}
+
+	tokenResponse := &oauth2TokenResponse{}
+	if err = json.Unmarshal(bodyBytes, tokenResponse); err != nil {
+		return nil, fmt.Errorf("error parsing OAuth2 token response: %v", err)
+	}
+
+	if tokenResponse.AccessToken == "" {
+		return nil, fmt.Errorf("OAuth2 token response has no access_token")
+	}
+
+	if tokenResponse.TokenType != "" && !strings.EqualFold(tokenResponse.TokenType, "bearer") {
+		return nil, fmt.Errorf("OAuth2 token type %s is not supported", tokenResponse.TokenType)
+	}
+
+	token := &oauth2Token{accessToken: tokenResponse.AccessToken}
+
+	if len(tokenResponse.ExpiresIn) > 0 && string(tokenResponse.ExpiresIn) != "null" {
+		expiresIn, err := strconv.ParseInt(strings.Trim(string(tokenResponse.ExpiresIn), `"`), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing OAuth2 token expires_in: %v", err)
+		}
+
+		token.expiry = start.Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+func (ac *apiCheck) getAuthFailedStatusGaugeObserver(authErr *authError) *int64GaugeObserver {
+	if authErr == nil {
+		debugLogger.Println("No auth failed status")
+		return nil
+	}
+
+	attributes := []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, authFailedStatusMetricStatusLabelValue),
+	}
+
+	return newInt64GaugeObserver(statusMetricName, statusMetricValue, append(attributes, ac.getRequestErrorLabels(authErr)...), statusObserverDescription)
+}
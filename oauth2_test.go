@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOAuth2Check(t *testing.T, oauth2 *oauth2Config) *apiCheck {
+	check, err := newApiCheck(&checkConfig{
+		Name:               "test",
+		URL:                "https://example.api:1234/users",
+		Method:             http.MethodGet,
+		Auth:               authConfig{OAuth2: oauth2},
+		Timeout:            "5s",
+		ExpectedStatusCode: "200",
+	})
+	require.NoError(t, err)
+
+	return check
+}
+
+func TestGetGaugeObservers_OAuth2(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	tokenURL := "https://auth.example.api/oauth2/token"
+
+	httpmock.RegisterResponder(http.MethodPost, tokenURL,
+		func(request *http.Request) (*http.Response, error) {
+			clientID, clientSecret, ok := request.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "api-status", clientID)
+			assert.Equal(t, "s3cr3t%2B", clientSecret)
+
+			require.NoError(t, request.ParseForm())
+			assert.Equal(t, "client_credentials", request.PostForm.Get("grant_type"))
+			assert.Equal(t, "users:read orders:read", request.PostForm.Get("scope"))
+			assert.Equal(t, "https://example.api", request.PostForm.Get("audience"))
+			assert.Empty(t, request.PostForm.Get("client_secret"))
+
+			return httpmock.NewStringResponse(http.StatusOK, `{"access_token": "token-1", "token_type": "Bearer", "expires_in": 3600}`), nil
+		})
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, "Bearer token-1", request.Header.Get("Authorization"))
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	config := &oauth2Config{
+		TokenURL:     tokenURL,
+		ClientID:     "api-status",
+		ClientSecret: "s3cr3t+",
+		Scopes:       []string{"users:read", "orders:read"},
+		Audience:     "https://example.api",
+	}
+
+	// Each invocation creates its checks again, and a warm invocation reuses the token
+	for invocation := 0; invocation < 2; invocation++ {
+		result := getTestCheckResult(context.Background(), t, newTestOAuth2Check(t, config))
+		assert.Equal(t, successStatusMetricStatusLabelValue, result.Status)
+	}
+
+	callCounts := httpmock.GetCallCountInfo()
+	assert.Equal(t, 1, callCounts["POST "+tokenURL])
+	assert.Equal(t, 2, callCounts["GET https://example.api:1234/users"])
+}
+
+func TestGetGaugeObservers_OAuth2ExpiringToken(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	tokenURL := "https://auth.example.api/oauth2/expiring-token"
+
+	// The token expires within the expiry margin, so it is requested for each run
+	httpmock.RegisterResponder(http.MethodPost, tokenURL,
+		func(request *http.Request) (*http.Response, error) {
+			require.NoError(t, request.ParseForm())
+			assert.Equal(t, "api-status", request.PostForm.Get("client_id"))
+			assert.Equal(t, "secret", request.PostForm.Get("client_secret"))
+
+			return httpmock.NewStringResponse(http.StatusOK, `{"access_token": "token", "expires_in": "10"}`), nil
+		})
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+
+	check := newTestOAuth2Check(t, &oauth2Config{TokenURL: tokenURL, ClientID: "api-status", ClientSecret: "secret", AuthStyle: bodyOAuth2AuthStyle})
+
+	for run := 0; run < 2; run++ {
+		result := getTestCheckResult(context.Background(), t, check)
+		assert.Equal(t, successStatusMetricStatusLabelValue, result.Status)
+	}
+
+	assert.Equal(t, 2, httpmock.GetCallCountInfo()["POST "+tokenURL])
+}
+
+func TestGetGaugeObservers_OAuth2RejectedToken(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	tokenURL := "https://auth.example.api/oauth2/rejected-token"
+
+	httpmock.RegisterResponder(http.MethodPost, tokenURL,
+		httpmock.NewStringResponder(http.StatusOK, `{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`))
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusUnauthorized, ""))
+
+	check := newTestOAuth2Check(t, &oauth2Config{TokenURL: tokenURL, ClientID: "api-status"})
+
+	// The API rejecting the token drops it, so the next run requests a new token
+	for run := 0; run < 2; run++ {
+		result := getTestCheckResult(context.Background(), t, check)
+		assert.Equal(t, noMatchStatusCodeStatusMetricStatusLabelValue, result.Status)
+	}
+
+	assert.Equal(t, 2, httpmock.GetCallCountInfo()["POST "+tokenURL])
+}
+
+func TestGetGaugeObservers_AuthFailed(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	for tokenURL, responder := range map[string]httpmock.Responder{
+		"https://auth.example.api/oauth2/invalid-client": httpmock.NewStringResponder(http.StatusUnauthorized, `{"error": "invalid_client"}`),
+		"https://auth.example.api/oauth2/no-token":       httpmock.NewStringResponder(http.StatusOK, `{"token_type": "Bearer"}`),
+		"https://auth.example.api/oauth2/mac-token":      httpmock.NewStringResponder(http.StatusOK, `{"access_token": "token", "token_type": "mac"}`),
+		"https://auth.example.api/oauth2/not-json":       httpmock.NewStringResponder(http.StatusOK, `<html></html>`),
+	} {
+		httpmock.RegisterResponder(http.MethodPost, tokenURL, responder)
+
+		check := newTestOAuth2Check(t, &oauth2Config{TokenURL: tokenURL, ClientID: "api-status"})
+		check.retryAttempts = 3
+
+		result := getTestCheckResult(context.Background(), t, check)
+		assert.Equal(t, authFailedStatusMetricStatusLabelValue, result.Status, tokenURL)
+		assert.NotEmpty(t, result.Labels[statusMetricErrorLabelName], tokenURL)
+		assert.Equal(t, otherErrorTypeLabelValue, result.Labels[errorTypeLabelName], tokenURL)
+	}
+
+	// Without a token, the API is not requested
+	assert.Equal(t, 0, httpmock.GetCallCountInfo()["GET https://example.api:1234/users"])
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["POST https://auth.example.api/oauth2/invalid-client"])
+}
+
+func TestGetGaugeObservers_OAuth2InsecureSkipVerify(t *testing.T) {
+	tokenRequests := 0
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/oauth2/token" {
+			tokenRequests++
+			_, _ = writer.Write([]byte(`{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}`))
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	check, err := newApiCheck(&checkConfig{
+		Name:               "test",
+		URL:                server.URL + "/users",
+		Method:             http.MethodGet,
+		Auth:               authConfig{OAuth2: &oauth2Config{TokenURL: server.URL + "/oauth2/token", ClientID: "api-status", ClientSecret: "secret"}},
+		Timeout:            "5s",
+		ExpectedStatusCode: "200",
+		TLS:                tlsConfig{InsecureSkipVerify: true},
+	})
+	require.NoError(t, err)
+
+	// The check's TLS settings are not used for the token endpoint, so the secret is not sent to an unverified server
+	result := getTestCheckResult(context.Background(), t, check)
+	assert.Equal(t, authFailedStatusMetricStatusLabelValue, result.Status)
+	assert.Equal(t, tlsErrorTypeLabelValue, result.Labels[errorTypeLabelName])
+	assert.Equal(t, 0, tokenRequests)
+}
+
+func TestNewApiCheck_BadOAuth2Config(t *testing.T) {
+	for _, auth := range []authConfig{
+		{OAuth2: &oauth2Config{ClientID: "api-status"}},
+		{OAuth2: &oauth2Config{TokenURL: "auth.example.api", ClientID: "api-status"}},
+		{OAuth2: &oauth2Config{TokenURL: "https://auth.example.api/oauth2/token"}},
+		{OAuth2: &oauth2Config{TokenURL: "https://auth.example.api/oauth2/token", ClientID: "api-status", AuthStyle: "query"}},
+		{OAuth2: &oauth2Config{TokenURL: "https://auth.example.api/oauth2/token", ClientID: "api-status"}, BearerToken: "token"},
+	} {
+		_, err := newApiCheck(&checkConfig{
+			Name:               "test",
+			URL:                "https://example.api:1234/users",
+			Method:             http.MethodGet,
+			Auth:               auth,
+			Timeout:            "5s",
+			ExpectedStatusCode: "200",
+		})
+		assert.Error(t, err, auth.OAuth2)
+	}
+}
+
+func TestGetEnvCheckConfig_OAuth2(t *testing.T) {
+	config, err := getEnvCheckConfig()
+	require.NoError(t, err)
+	assert.Nil(t, config.Auth.OAuth2)
+
+	err = os.Setenv(oauth2TokenURLEnvName, "https://auth.example.api/oauth2/token")
+	require.NoError(t, err)
+
+	err = os.Setenv(oauth2ClientIDEnvName, "api-status")
+	require.NoError(t, err)
+
+	err = os.Setenv(oauth2ClientSecretEnvName, "secret")
+	require.NoError(t, err)
+
+	err = os.Setenv(oauth2ScopesEnvName, "users:read, orders:read")
+	require.NoError(t, err)
+
+	err = os.Setenv(oauth2AudienceEnvName, "https://example.api")
+	require.NoError(t, err)
+
+	config, err = getEnvCheckConfig()
+	require.NoError(t, err)
+	assert.Equal(t, &oauth2Config{
+		TokenURL:     "https://auth.example.api/oauth2/token",
+		ClientID:     "api-status",
+		ClientSecret: "secret",
+		Scopes:       []string{"users:read", "orders:read"},
+		Audience:     "https://example.api",
+	}, config.Auth.OAuth2)
+
+	os.Clearenv()
+}
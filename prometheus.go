@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/metric/number"
+)
+
+const (
+	metricsListenAddressEnvName = "METRICS_LISTEN_ADDRESS"
+	metricsPath                 = "/metrics"
+	prometheusContentType       = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var prometheusLabelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Returns an HTTP handler that serves the metrics of the gauge observers in the Prometheus text exposition format
+func newPrometheusMetricsHandler(getGaugeObservers func() []metricRegister) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", prometheusContentType)
+
+		if err := writePrometheusMetrics(writer, getGaugeObserversRecords(getGaugeObservers())); err != nil {
+			errorLogger.Printf("Error writing metrics: %v\n", err)
+		}
+	})
+
+	return mux
+}
+
+// Returns the values of the gauge observers, sorted by name and labels
+func getGaugeObserversRecords(metricRegisters []metricRegister) []*metricRecord {
+	records := make([]*metricRecord, 0, len(metricRegisters))
+
+	for _, metricReg := range metricRegisters {
+		if record := getGaugeObserverRecord(metricReg); record != nil {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+
+		return formatLabels(records[i].Labels) < formatLabels(records[j].Labels)
+	})
+
+	return records
+}
+
+// Writes the metrics in the Prometheus text exposition format, the records must be sorted by name
+func writePrometheusMetrics(output io.Writer, records []*metricRecord) error {
+	writer := bufio.NewWriter(output)
+	previousName := ""
+
+	for _, record := range records {
+		if record.Name != previousName {
+			_, _ = fmt.Fprintf(writer, "# HELP %s %s\n", record.Name, record.description)
+			_, _ = fmt.Fprintf(writer, "# TYPE %s gauge\n", record.Name)
+			previousName = record.Name
+		}
+
+		labelNames := make([]string, 0, len(record.Labels))
+		for labelName := range record.Labels {
+			labelNames = append(labelNames, labelName)
+		}
+
+		sort.Strings(labelNames)
+
+		labels := make([]string, 0, len(labelNames))
+		for _, labelName := range labelNames {
+			labels = append(labels, fmt.Sprintf(`%s="%s"`, labelName, prometheusLabelValueReplacer.Replace(record.Labels[labelName])))
+		}
+
+		value := strconv.FormatFloat(record.Value, 'g', -1, 64)
+		if record.numberKind == number.Int64Kind {
+			value = strconv.FormatInt(int64(record.Value), 10)
+		}
+
+		_, _ = fmt.Fprintf(writer, "%s{%s} %s\n", record.Name, strings.Join(labels, ","), value)
+	}
+
+	return writer.Flush()
+}
+
+// Serves the metrics endpoint until the context is done
+func servePrometheusMetrics(ctx context.Context, listener net.Listener, handler http.Handler) {
+	server := &http.Server{Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+
+		if err := server.Shutdown(context.Background()); err != nil {
+			errorLogger.Printf("Error shutting down metrics server: %v\n", err)
+		}
+	}()
+
+	infoLogger.Printf("Serving metrics on %s%s\n", listener.Addr(), metricsPath)
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		errorLogger.Printf("Error serving metrics: %v\n", err)
+	}
+}
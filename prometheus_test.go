@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/number"
+)
+
+func TestWritePrometheusMetrics(t *testing.T) {
+	records := []*metricRecord{
+		{Name: responseTimeMetricName, Value: 12.5, Labels: map[string]string{checkNameLabelName: "users", urlLabelName: "https://example.api:1234/users"}, description: "API response time", numberKind: number.Float64Kind},
+		{Name: statusMetricName, Value: 1, Labels: map[string]string{checkNameLabelName: "orders", statusMetricErrorLabelName: "line 1\nsaid \"no\" \\ bye"}, description: "API status", numberKind: number.Int64Kind},
+		{Name: statusMetricName, Value: 1, Labels: map[string]string{checkNameLabelName: "users", statusMetricStatusLabelName: successStatusMetricStatusLabelValue}, description: "API status", numberKind: number.Int64Kind},
+	}
+
+	var output bytes.Buffer
+	err := writePrometheusMetrics(&output, records)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# HELP api_status_response_time API response time
+# TYPE api_status_response_time gauge
+api_status_response_time{check_name="users",url="https://example.api:1234/users"} 12.5
+# HELP api_status_status API status
+# TYPE api_status_status gauge
+api_status_status{check_name="orders",error="line 1\nsaid \"no\" \\ bye"} 1
+api_status_status{check_name="users",status="success"} 1
+`, output.String())
+}
+
+func TestRunDaemon_PrometheusMetrics(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users", "interval": "50ms"}]}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(metricsExportersEnvName, noMetricsExporters)
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, "success"))
+
+	apiStatus, err := newLogzioApiStatus(context.Background(), daemonMode)
+	require.NoError(t, err)
+
+	daemon, err := newApiStatusDaemon(apiStatus, "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	daemonErrors := make(chan error, 1)
+
+	go func() {
+		daemonErrors <- daemon.run(ctx)
+	}()
+
+	// httpmock replaces the default transport
+	client := &http.Client{Transport: &http.Transport{}}
+	metricsURL := "http://" + daemon.metricsListener.Addr().String() + metricsPath
+	var metrics string
+
+	require.Eventually(t, func() bool {
+		response, err := client.Get(metricsURL)
+		if err != nil {
+			return false
+		}
+
+		defer closeResponseBody(response.Body)
+
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, prometheusContentType, response.Header.Get("Content-Type"))
+
+		metrics = string(body)
+
+		return bytes.Contains(body, []byte(statusMetricName))
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Contains(t, metrics, "# TYPE api_status_status gauge\n")
+	assert.Contains(t, metrics, `api_status_status{check_name="users",method="GET",response_status_code="200",status="success",url="https://example.api:1234/users"} 1`)
+	assert.Contains(t, metrics, `api_status_response_body_length{check_name="users",method="GET",unit="bytes",url="https://example.api:1234/users"} 7`)
+	assert.Contains(t, metrics, "# TYPE api_status_response_time gauge\n")
+
+	cancel()
+	require.NoError(t, <-daemonErrors)
+
+	_, err = client.Get(metricsURL)
+	assert.Error(t, err)
+
+	os.Clearenv()
+}
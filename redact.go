@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	sensitiveQueryParamsEnvName = "SENSITIVE_QUERY_PARAMS"
+	sensitiveHeadersEnvName     = "SENSITIVE_HEADERS"
+	redactedValue               = "REDACTED"
+)
+
+var (
+	defaultSensitiveQueryParams = []string{
+		"access_token",
+		"api-key",
+		"api_key",
+		"apikey",
+		"auth",
+		"client_secret",
+		"key",
+		"password",
+		"secret",
+		"sig",
+		"signature",
+		"token",
+		"x-amz-credential",
+		"x-amz-security-token",
+		"x-amz-signature",
+	}
+	defaultSensitiveHeaders = []string{
+		"Authorization",
+		"Cookie",
+		"Proxy-Authorization",
+		"Set-Cookie",
+		"X-Amz-Security-Token",
+		"X-Api-Key",
+		"X-Auth-Token",
+	}
+	urlRegexp      = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+	secretRedactor = newRedactor(nil, nil)
+)
+
+// Masks the userinfo and sensitive query parameters of URLs, and the values of sensitive headers
+type redactor struct {
+	sensitiveQueryParams map[string]bool
+	sensitiveHeaders     map[string]bool
+}
+
+// The sensitive query parameters and headers are added to the default ones
+func newRedactor(sensitiveQueryParams []string, sensitiveHeaders []string) *redactor {
+	r := &redactor{
+		sensitiveQueryParams: make(map[string]bool),
+		sensitiveHeaders:     make(map[string]bool),
+	}
+
+	for _, queryParam := range append(defaultSensitiveQueryParams, sensitiveQueryParams...) {
+		r.sensitiveQueryParams[strings.ToLower(queryParam)] = true
+	}
+
+	for _, header := range append(defaultSensitiveHeaders, sensitiveHeaders...) {
+		r.sensitiveHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	return r
+}
+
+// Sets the redactor of the labels and logs from the environment variables
+func setSecretRedactor() {
+	secretRedactor = newRedactor(splitList(os.Getenv(sensitiveQueryParamsEnvName)), splitList(os.Getenv(sensitiveHeadersEnvName)))
+}
+
+// Splits a comma separated list, ignoring spaces and empty items
+func splitList(listString string) []string {
+	items := make([]string, 0)
+
+	for _, item := range strings.Split(listString, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Returns the URL with its userinfo and the values of its sensitive query parameters masked, or as is if there is nothing to mask
+func (r *redactor) redactURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	rawQuery := r.redactQuery(parsedURL.RawQuery)
+	if parsedURL.User == nil && rawQuery == parsedURL.RawQuery {
+		return rawURL
+	}
+
+	if parsedURL.User != nil {
+		parsedURL.User = url.User(redactedValue)
+	}
+
+	parsedURL.RawQuery = rawQuery
+
+	return parsedURL.String()
+}
+
+// Masks the values of sensitive query parameters, keeping the parameters' order
+func (r *redactor) redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+
+	queryParams := strings.Split(rawQuery, "&")
+
+	for index, queryParam := range queryParams {
+		key := strings.SplitN(queryParam, "=", 2)[0]
+
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+
+		if r.sensitiveQueryParams[strings.ToLower(name)] {
+			queryParams[index] = key + "=" + redactedValue
+		}
+	}
+
+	return strings.Join(queryParams, "&")
+}
+
+// Masks the URLs in the text, such as the URLs in errors of requests
+func (r *redactor) redactText(text string) string {
+	return urlRegexp.ReplaceAllStringFunc(text, r.redactURL)
+}
+
+func (r *redactor) isSensitiveHeader(name string) bool {
+	return r.sensitiveHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// Returns the header's value, masked if the header is sensitive
+func (r *redactor) redactHeaderValue(name string, value string) string {
+	if r.isSensitiveHeader(name) {
+		return redactedValue
+	}
+
+	return value
+}
+
+// Returns the check's URL as it is set in labels
+func (ac *apiCheck) getRedactedURL() string {
+	return secretRedactor.redactURL(ac.url)
+}
+
+// Masks the URLs in every line written to the output, the log lines are written with one write each
+type redactingWriter struct {
+	lock   sync.Mutex
+	output io.Writer
+}
+
+func newRedactingWriter(output io.Writer) *redactingWriter {
+	return &redactingWriter{output: output}
+}
+
+func (rw *redactingWriter) setOutput(output io.Writer) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	rw.output = output
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	if _, err := io.WriteString(rw.output, secretRedactor.redactText(string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	retryAttemptsEnvName              = "RETRY_ATTEMPTS"
+	retryBackoffEnvName               = "RETRY_BACKOFF"
+	retryableEnvName                  = "RETRYABLE"
+	defaultRetryAttempts              = 1
+	defaultRetryBackoff               = time.Second
+	timeoutRetryableOutcome           = "timeout"
+	connectionErrorRetryableOutcome   = "connection_error"
+	serverErrorRetryableOutcome       = "5xx"
+	attemptsMetricName                = meterName + "_attempts"
+	totalResponseTimeMetricName       = meterName + "_total_response_time"
+	maxDiscardedRetryResponseBodySize = 64 * 1024
+)
+
+var retryableOutcomes = []string{timeoutRetryableOutcome, connectionErrorRetryableOutcome, serverErrorRetryableOutcome}
+
+// Attempts (including the first one) and the outcomes that are retried, with exponential backoff between attempts
+type retryConfig struct {
+	Attempts  int      `yaml:"attempts"`
+	Backoff   string   `yaml:"backoff"`
+	Retryable []string `yaml:"retryable"`
+}
+
+// The last attempt of a check's request
+type apiHttpAttempt struct {
+	response     *http.Response
+	responseTime float64
+	timings      *httpTimings
+	err          error
+	// Failing to get the request's credentials, in which case there is no response
+	authErr  *authError
+	attempts int
+	// Milliseconds from the first attempt until the last response, including the backoff between attempts
+	totalResponseTime float64
+}
+
+// Sets the check's retries from the config, where no attempts means a single attempt, and no retryable outcomes means all of them
+func (ac *apiCheck) setRetry(config retryConfig) error {
+	ac.retryAttempts = config.Attempts
+	if ac.retryAttempts == 0 {
+		ac.retryAttempts = defaultRetryAttempts
+	}
+
+	if ac.retryAttempts < 1 {
+		return fmt.Errorf("retry attempts must be a positive number")
+	}
+
+	backoff, err := parseOptionalDuration(config.Backoff)
+	if err != nil {
+		return fmt.Errorf("error parsing retry backoff: %v", err)
+	}
+
+	ac.retryBackoff = backoff
+	if ac.retryBackoff == 0 {
+		ac.retryBackoff = defaultRetryBackoff
+	}
+
+	retryable := config.Retryable
+	if len(retryable) == 0 {
+		retryable = retryableOutcomes
+	}
+
+	ac.retryableOutcomes = make(map[string]bool)
+
+	for _, outcome := range retryable {
+		if !isRetryableOutcome(outcome) {
+			return fmt.Errorf("retryable outcome %s must be one of %s", outcome, strings.Join(retryableOutcomes, ", "))
+		}
+
+		ac.retryableOutcomes[outcome] = true
+	}
+
+	return nil
+}
+
+func isRetryableOutcome(outcome string) bool {
+	for _, retryableOutcome := range retryableOutcomes {
+		if outcome == retryableOutcome {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sends the check's request until its outcome is not retryable or the attempts run out, and returns the last attempt.
+// The returned cancel function must be called after the last attempt's response body was read.
+func (ac *apiCheck) getApiHttpResponseWithRetries(ctx context.Context) (*apiHttpAttempt, context.CancelFunc, error) {
+	start := time.Now()
+	attempt := &apiHttpAttempt{}
+
+	for {
+		attempt.attempts++
+		attempt.timings = &httpTimings{}
+
+		// Each attempt's timeout applies on top of the invocation's deadline
+		attemptCtx, cancel := context.WithTimeout(ctx, ac.responseTimeout)
+
+		request, err := ac.createApiHttpRequest(attemptCtx)
+		if errors.As(err, &attempt.authErr) {
+			return attempt, cancel, nil
+		}
+
+		if err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("error creating API HTTP request: %v", err)
+		}
+
+		// The request is traced once it was created, so requests for its credentials (for example: of OAuth2 tokens) are not
+		request = request.WithContext(httptrace.WithClientTrace(attemptCtx, attempt.timings.clientTrace()))
+
+		attempt.response, attempt.responseTime, attempt.err = ac.getApiHttpResponse(request)
+		attempt.totalResponseTime = float64(time.Since(start)) / float64(time.Millisecond)
+
+		// A rejected token may have been revoked before it expired, so the next attempt or run gets a new one
+		if ac.oauth2 != nil && attempt.err == nil && attempt.response.StatusCode == http.StatusUnauthorized {
+			ac.oauth2.invalidateToken()
+		}
+
+		if attempt.attempts >= ac.retryAttempts || !ac.isRetryable(attempt.response, attempt.err) || !ac.waitRetryBackoff(ctx, attempt.attempts) {
+			return attempt, cancel, nil
+		}
+
+		debugLogger.Printf("Retrying check %s (attempt %d of %d)...\n", ac.name, attempt.attempts+1, ac.retryAttempts)
+
+		if attempt.response != nil {
+			// Reading the body lets the connection be reused by the next attempt
+			_, _ = io.CopyN(io.Discard, attempt.response.Body, maxDiscardedRetryResponseBodySize)
+			closeResponseBody(attempt.response.Body)
+		}
+
+		cancel()
+	}
+}
+
+func (ac *apiCheck) isRetryable(response *http.Response, err error) bool {
+	if err != nil {
+		if netError, ok := err.(net.Error); ok && netError.Timeout() {
+			return ac.retryableOutcomes[timeoutRetryableOutcome]
+		}
+
+		return ac.retryableOutcomes[connectionErrorRetryableOutcome]
+	}
+
+	// An expected 5xx is the check's success, so retrying it would only repeat the same outcome
+	if ac.expectedResponseStatusCode.matches(response.StatusCode) {
+		return false
+	}
+
+	return response.StatusCode >= http.StatusInternalServerError && response.StatusCode <= 599 && ac.retryableOutcomes[serverErrorRetryableOutcome]
+}
+
+// Waits the backoff of the attempt (doubled after each attempt), returns false if the context was done first
+func (ac *apiCheck) waitRetryBackoff(ctx context.Context, attempt int) bool {
+	timer := time.NewTimer(ac.retryBackoff << (attempt - 1))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Returns gauge observers of the attempts and the total response time, for checks with retries
+func (ac *apiCheck) getRetryGaugeObservers(attempt *apiHttpAttempt) []metricRegister {
+	if ac.retryAttempts <= 1 {
+		return nil
+	}
+
+	return []metricRegister{
+		newInt64GaugeObserver(attemptsMetricName, int64(attempt.attempts), []attribute.KeyValue{
+			attribute.String(checkNameLabelName, ac.name),
+			attribute.String(urlLabelName, ac.getRedactedURL()),
+			attribute.String(methodLabelName, ac.method),
+		}, "API request attempts"),
+		newFloat64GaugeObserver(totalResponseTimeMetricName, attempt.totalResponseTime, []attribute.KeyValue{
+			attribute.String(checkNameLabelName, ac.name),
+			attribute.String(urlLabelName, ac.getRedactedURL()),
+			attribute.String(methodLabelName, ac.method),
+			attribute.String(unitLabelName, responseTimeMetricUnitLabelValue),
+		}, "API response time of all attempts"),
+	}
+}
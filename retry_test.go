@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestRetryCheckResults(t *testing.T, checks string) []*checkResult {
+	err := os.Setenv(checksEnvName, checks)
+	require.NoError(t, err)
+
+	apiStatus, err := newCheckCommandApiStatus("")
+	require.NoError(t, err)
+
+	_, checkResults, err := apiStatus.runChecks()
+	require.NoError(t, err)
+
+	os.Clearenv()
+
+	return checkResults
+}
+
+func TestRunChecks_Retries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	statusCodes := []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		func(request *http.Request) (*http.Response, error) {
+			statusCode := statusCodes[0]
+			statusCodes = statusCodes[1:]
+
+			return httpmock.NewStringResponse(statusCode, "success"), nil
+		})
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/orders",
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, "unavailable"))
+
+	checkResults := getTestRetryCheckResults(t, `
+checks:
+  - name: users
+    url: https://example.api:1234/users
+    retry:
+      attempts: 3
+      backoff: 1ms
+  - name: orders
+    url: https://example.api:1234/orders
+    retry:
+      attempts: 3
+      backoff: 1ms
+      retryable: [timeout, connection_error]
+  - name: products
+    url: https://example.api:1234/products
+    retry:
+      attempts: 2
+      backoff: 1ms
+`)
+	require.Len(t, checkResults, 3)
+
+	users := checkResults[0]
+	assert.Equal(t, successStatusMetricStatusLabelValue, users.Status)
+	assert.Equal(t, float64(3), users.metrics[attemptsMetricName])
+	assert.GreaterOrEqual(t, users.metrics[totalResponseTimeMetricName], users.metrics[responseTimeMetricName])
+	assert.Empty(t, statusCodes)
+
+	// 5xx is not retryable for orders
+	orders := checkResults[1]
+	assert.Equal(t, noMatchStatusCodeStatusMetricStatusLabelValue, orders.Status)
+	assert.Equal(t, float64(1), orders.metrics[attemptsMetricName])
+
+	// Connection errors are retried until the attempts run out
+	products := checkResults[2]
+	assert.Equal(t, connectionFailedStatusMetricStatusLabelValue, products.Status)
+	assert.Equal(t, float64(2), products.metrics[attemptsMetricName])
+	assert.Contains(t, products.metrics, totalResponseTimeMetricName)
+	assert.NotContains(t, products.metrics, responseTimeMetricName)
+
+	callCounts := httpmock.GetCallCountInfo()
+	assert.Equal(t, 3, callCounts["GET https://example.api:1234/users"])
+	assert.Equal(t, 1, callCounts["GET https://example.api:1234/orders"])
+}
+
+func TestRunChecks_NoRetries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, "unavailable"))
+
+	checkResults := getTestRetryCheckResults(t, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+	require.Len(t, checkResults, 1)
+
+	assert.Equal(t, noMatchStatusCodeStatusMetricStatusLabelValue, checkResults[0].Status)
+	assert.NotContains(t, checkResults[0].metrics, attemptsMetricName)
+	assert.NotContains(t, checkResults[0].metrics, totalResponseTimeMetricName)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func TestRunChecks_ExpectedServerErrorNotRetried(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/maintenance",
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, "maintenance"))
+
+	checkResults := getTestRetryCheckResults(t, `
+checks:
+  - name: maintenance
+    url: https://example.api:1234/maintenance
+    expected_status_code: 503
+    retry:
+      attempts: 3
+      backoff: 1ms
+`)
+	require.Len(t, checkResults, 1)
+
+	assert.Equal(t, successStatusMetricStatusLabelValue, checkResults[0].Status)
+	assert.Equal(t, float64(1), checkResults[0].metrics[attemptsMetricName])
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func TestIsRetryable(t *testing.T) {
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{expectedResponseStatusCode: expectedResponseStatusCode}
+	err = check.setRetry(retryConfig{Attempts: 2, Retryable: []string{timeoutRetryableOutcome}})
+	require.NoError(t, err)
+
+	timeoutError := &url.Error{Op: "Get", URL: "https://example.api:1234/users", Err: context.DeadlineExceeded}
+	connectionError := &url.Error{Op: "Get", URL: "https://example.api:1234/users", Err: errors.New("connection reset by peer")}
+
+	assert.True(t, check.isRetryable(nil, timeoutError))
+	assert.False(t, check.isRetryable(nil, connectionError))
+	assert.False(t, check.isRetryable(&http.Response{StatusCode: http.StatusInternalServerError}, nil))
+
+	err = check.setRetry(retryConfig{Attempts: 2})
+	require.NoError(t, err)
+
+	assert.True(t, check.isRetryable(nil, connectionError))
+	assert.True(t, check.isRetryable(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.False(t, check.isRetryable(&http.Response{StatusCode: http.StatusNotFound}, nil))
+	assert.False(t, check.isRetryable(&http.Response{StatusCode: http.StatusOK}, nil))
+
+	check.expectedResponseStatusCode, err = newStatusCodeExpression("503")
+	require.NoError(t, err)
+
+	assert.False(t, check.isRetryable(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.True(t, check.isRetryable(&http.Response{StatusCode: http.StatusBadGateway}, nil))
+}
+
+func TestNewApiCheck_BadRetry(t *testing.T) {
+	for _, retry := range []retryConfig{
+		{Attempts: -1},
+		{Attempts: 2, Backoff: "1"},
+		{Attempts: 2, Backoff: "-1s"},
+		{Attempts: 2, Retryable: []string{"4xx"}},
+	} {
+		_, err := newApiCheck(&checkConfig{Name: "users", URL: "https://example.api:1234/users", Method: http.MethodGet, Timeout: "10s", ExpectedStatusCode: "200", Retry: retry})
+		assert.Error(t, err, retry)
+	}
+}
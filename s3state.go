@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	stateS3BucketEnvName   = "STATE_S3_BUCKET"
+	stateS3KeyEnvName      = "STATE_S3_KEY"
+	stateS3EndpointEnvName = "STATE_S3_ENDPOINT"
+	s3StateStoreType       = "s3"
+	defaultStateS3Key      = "api-status-state.json"
+	s3ContentHashHeader    = "X-Amz-Content-Sha256"
+	s3RequestTimeout       = 10 * time.Second
+	// Writes that lost a race with another function instance read the states again, up to this many times
+	maxS3StateWriteAttempts = 3
+	maxS3ErrorResponseBody  = 256
+)
+
+// Keeps the states in a JSON object in S3, so Lambda functions keep them across cold starts and instances.
+// Writes are conditional on the object's ETag, so concurrent writers don't overwrite each other's states.
+type s3StateStore struct {
+	objectURL string
+	signer    *sigV4Signer
+	client    *http.Client
+	lock      sync.Mutex
+}
+
+// The S3 object of the states and its ETag, which is empty if the object does not exist
+type s3States struct {
+	states map[string]*checkState
+	eTag   string
+}
+
+// Returns a store of the object STATE_S3_KEY in the bucket STATE_S3_BUCKET, in the Lambda function's region.
+// STATE_S3_ENDPOINT replaces the bucket's endpoint (for example: an S3 compatible storage), with path style URLs.
+func getS3StateStore() (stateStore, error) {
+	bucket := os.Getenv(stateS3BucketEnvName)
+	if bucket == "" {
+		return nil, fmt.Errorf("%s must be set for the %s state store", stateS3BucketEnvName, s3StateStoreType)
+	}
+
+	key := os.Getenv(stateS3KeyEnvName)
+	if key == "" {
+		key = defaultStateS3Key
+	}
+
+	region := os.Getenv(awsRegionEnvName)
+	if region == "" {
+		return nil, fmt.Errorf("%s must be set for the %s state store", awsRegionEnvName, s3StateStoreType)
+	}
+
+	endpoint := os.Getenv(stateS3EndpointEnvName)
+	objectURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, escapeSigV4Path(key))
+
+	if endpoint != "" {
+		objectURL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucket, escapeSigV4Path(key))
+	}
+
+	return newS3StateStore(objectURL, region), nil
+}
+
+func newS3StateStore(objectURL string, region string) *s3StateStore {
+	return &s3StateStore{
+		objectURL: objectURL,
+		signer:    &sigV4Signer{service: s3SigV4Service, region: region},
+		client:    &http.Client{Timeout: s3RequestTimeout},
+	}
+}
+
+func (sss *s3StateStore) getStates(ctx context.Context) (map[string]*checkState, error) {
+	sss.lock.Lock()
+	defer sss.lock.Unlock()
+
+	object, err := sss.readStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return object.states, nil
+}
+
+// Reads the states, updates them and writes them if the object's ETag did not change. If another writer changed the
+// object since it was read, the whole update is done again on the states read again.
+func (sss *s3StateStore) updateStates(ctx context.Context, update func(states map[string]*checkState)) error {
+	sss.lock.Lock()
+	defer sss.lock.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		object, err := sss.readStates(ctx)
+		if err != nil {
+			return err
+		}
+
+		update(object.states)
+
+		written, err := sss.writeStates(ctx, object)
+		if err != nil {
+			return err
+		}
+
+		if written {
+			return nil
+		}
+
+		if attempt == maxS3StateWriteAttempts {
+			return fmt.Errorf("error writing state object %s: changed by another writer %d times", sss.objectURL, attempt)
+		}
+
+		debugLogger.Printf("State object changed by another writer, reading it again (attempt %d)...\n", attempt)
+	}
+}
+
+// Returns no states if the object does not exist yet
+func (sss *s3StateStore) readStates(ctx context.Context) (*s3States, error) {
+	response, body, err := sss.do(ctx, http.MethodGet, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error reading state object: %v", err)
+	}
+
+	object := &s3States{states: make(map[string]*checkState)}
+
+	if response.StatusCode == http.StatusNotFound {
+		return object, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error reading state object: %s", getS3Error(response, body))
+	}
+
+	if err = json.Unmarshal(body, &object.states); err != nil {
+		return nil, fmt.Errorf("error parsing state object: %v", err)
+	}
+
+	object.eTag = response.Header.Get("ETag")
+
+	return object, nil
+}
+
+// Writes the states if the object was not changed since it was read. Returns false if it was changed.
+func (sss *s3StateStore) writeStates(ctx context.Context, object *s3States) (bool, error) {
+	stateBytes, err := json.MarshalIndent(object.states, "", "  ")
+	if err != nil {
+		return false, err
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if object.eTag == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", object.eTag)
+	}
+
+	response, body, err := sss.do(ctx, http.MethodPut, header, stateBytes)
+	if err != nil {
+		return false, fmt.Errorf("error writing state object: %v", err)
+	}
+
+	// A conflicting conditional write returns 409, a failed precondition 412
+	switch response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return false, nil
+	default:
+		return false, fmt.Errorf("error writing state object: %s", getS3Error(response, body))
+	}
+}
+
+// Sends a request for the object signed with the execution role's credentials, and returns its response and body
+func (sss *s3StateStore) do(ctx context.Context, method string, header http.Header, body []byte) (*http.Response, []byte, error) {
+	credentials, err := getAwsCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, sss.objectURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	// S3 requires the payload's hash, which is signed with the other headers
+	request.Header.Set(s3ContentHashHeader, getSigV4PayloadHash(body))
+	sss.signer.sign(request, body, credentials, time.Now())
+
+	response, err := sss.client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer closeResponseBody(response.Body)
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, responseBody, nil
+}
+
+// The object outlives the function's instances
+func (sss *s3StateStore) persistent() bool {
+	return true
+}
+
+func getS3Error(response *http.Response, body []byte) string {
+	message, _ := truncateString(string(body), maxS3ErrorResponseBody)
+	return fmt.Sprintf("status code %d: %s", response.StatusCode, message)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A fake S3 bucket that keeps objects in memory, verifies the requests' signatures and honors conditional writes
+type testS3Server struct {
+	t       *testing.T
+	lock    sync.Mutex
+	objects map[string][]byte
+	// The requests by method
+	requests map[string]int
+	// Called before a write is handled, for example to simulate another writer
+	beforePut func()
+}
+
+func newTestS3Server(t *testing.T) (*testS3Server, *httptest.Server) {
+	s3Server := &testS3Server{t: t, objects: make(map[string][]byte), requests: make(map[string]int)}
+	server := httptest.NewServer(s3Server)
+	t.Cleanup(server.Close)
+
+	return s3Server, server
+}
+
+func getTestS3ETag(object []byte) string {
+	hash := md5.Sum(object)
+	return `"` + hex.EncodeToString(hash[:]) + `"`
+}
+
+func (tss *testS3Server) putObject(path string, object []byte) {
+	tss.lock.Lock()
+	defer tss.lock.Unlock()
+
+	tss.objects[path] = object
+}
+
+func (tss *testS3Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	require.NoError(tss.t, err)
+
+	if request.Header.Get(s3ContentHashHeader) != getSigV4PayloadHash(body) ||
+		!verifyTestSigV4Request(tss.t, request, body, s3SigV4Service, "us-east-1", testAwsSecretAccessKey) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if request.Method == http.MethodPut && tss.beforePut != nil {
+		tss.beforePut()
+	}
+
+	tss.lock.Lock()
+	defer tss.lock.Unlock()
+
+	tss.requests[request.Method]++
+	object, ok := tss.objects[request.URL.Path]
+
+	switch request.Method {
+	case http.MethodGet:
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = writer.Write([]byte("<Error><Code>NoSuchKey</Code></Error>"))
+			return
+		}
+
+		writer.Header().Set("ETag", getTestS3ETag(object))
+		_, _ = writer.Write(object)
+	case http.MethodPut:
+		if request.Header.Get("If-None-Match") == "*" && ok ||
+			request.Header.Get("If-Match") != "" && (!ok || request.Header.Get("If-Match") != getTestS3ETag(object)) {
+			writer.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		tss.objects[request.URL.Path] = body
+		writer.Header().Set("ETag", getTestS3ETag(body))
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func setTestS3StateStoreEnv(t *testing.T, endpoint string) {
+	for name, value := range map[string]string{
+		stateStoreEnvName:         s3StateStoreType,
+		stateS3BucketEnvName:      "api-status",
+		stateS3KeyEnvName:         "states/prod.json",
+		stateS3EndpointEnvName:    endpoint,
+		awsRegionEnvName:          "us-east-1",
+		awsAccessKeyIDEnvName:     testAwsAccessKeyID,
+		awsSecretAccessKeyEnvName: testAwsSecretAccessKey,
+	} {
+		err := os.Setenv(name, value)
+		require.NoError(t, err)
+	}
+}
+
+func TestS3StateStore(t *testing.T) {
+	_, server := newTestS3Server(t)
+	setTestS3StateStoreEnv(t, server.URL)
+
+	store, err := getStateStore()
+	require.NoError(t, err)
+	assert.True(t, store.persistent())
+
+	states, err := store.getStates(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, states)
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	usersState := &checkState{Status: responseTimeoutStatusMetricStatusLabelValue, Since: since, ConsecutiveFailures: 2, UpdatedAt: since.Add(time.Minute)}
+
+	err = putTestState(store, "users", usersState)
+	require.NoError(t, err)
+
+	err = putTestState(store, "orders", &checkState{Status: successStatusMetricStatusLabelValue, Since: since, UpdatedAt: since})
+	require.NoError(t, err)
+
+	// The states are kept across cold starts, which create the store again
+	coldStartStore, err := getStateStore()
+	require.NoError(t, err)
+
+	states, err = coldStartStore.getStates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, usersState, states["users"])
+	assert.Equal(t, successStatusMetricStatusLabelValue, states["orders"].Status)
+
+	os.Clearenv()
+}
+
+func TestS3StateStore_ConcurrentWriter(t *testing.T) {
+	s3Server, server := newTestS3Server(t)
+	store := newS3StateStore(server.URL+"/api-status/state.json", "us-east-1")
+
+	err := os.Setenv(awsAccessKeyIDEnvName, testAwsAccessKeyID)
+	require.NoError(t, err)
+
+	err = os.Setenv(awsSecretAccessKeyEnvName, testAwsSecretAccessKey)
+	require.NoError(t, err)
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err = putTestState(store, "users", &checkState{Status: successStatusMetricStatusLabelValue, Since: since, UpdatedAt: since})
+	require.NoError(t, err)
+
+	// Another function instance writes its check's state between this store's read and write
+	writes := 0
+	s3Server.beforePut = func() {
+		writes++
+		if writes == 1 {
+			s3Server.putObject("/api-status/state.json", []byte(`{"orders": {"status": "success", "consecutive_failures": 0}}`))
+		}
+	}
+
+	// The whole update is done again on the states read again
+	updates := 0
+	err = store.updateStates(context.Background(), func(states map[string]*checkState) {
+		updates++
+		states["users"] = getNextCheckState(states["users"], noMatchStatusCodeStatusMetricStatusLabelValue, since.Add(time.Minute))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, writes)
+	assert.Equal(t, 2, updates)
+
+	states, err := store.getStates(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, states["orders"])
+	assert.Equal(t, successStatusMetricStatusLabelValue, states["orders"].Status)
+	assert.Equal(t, noMatchStatusCodeStatusMetricStatusLabelValue, states["users"].Status)
+
+	// A writer that always wins the race fails the write after the last attempt
+	writes = 0
+	s3Server.beforePut = func() {
+		writes++
+		s3Server.putObject("/api-status/state.json", []byte(fmt.Sprintf(`{"orders": {"status": "success", "consecutive_failures": %d}}`, writes)))
+	}
+
+	err = putTestState(store, "users", &checkState{Status: successStatusMetricStatusLabelValue, Since: since, UpdatedAt: since})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "changed by another writer")
+	assert.Equal(t, maxS3StateWriteAttempts, writes)
+
+	os.Clearenv()
+}
+
+func TestRunChecks_S3StateStoreOneUpdate(t *testing.T) {
+	s3Server, server := newTestS3Server(t)
+	setTestS3StateStoreEnv(t, server.URL)
+
+	err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}, {"name": "orders", "url": "https://example.api:1234/orders"}, {"name": "items", "url": "https://example.api:1234/items"}]}`)
+	require.NoError(t, err)
+
+	err = os.Setenv(metricsExportersEnvName, stdoutMetricsExporterName)
+	require.NoError(t, err)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The fake S3 bucket is a real server
+	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip)
+
+	httpmock.RegisterResponder(http.MethodGet, `=~^https://example\.api:1234/`,
+		httpmock.NewStringResponder(http.StatusOK, ""))
+
+	apiStatus, err := newLogzioApiStatus(context.Background(), lambdaMode)
+	require.NoError(t, err)
+
+	for run := 1; run <= 2; run++ {
+		_, checkResults, err := apiStatus.runChecks()
+		require.NoError(t, err)
+
+		for _, result := range checkResults {
+			assert.Contains(t, result.metrics, consecutiveFailuresMetricName, result.Name)
+		}
+
+		// The states of all the checks are read and written once per run
+		assert.Equal(t, map[string]int{http.MethodGet: run, http.MethodPut: run}, s3Server.requests)
+	}
+
+	states, err := apiStatus.stateStore.getStates(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, states, 3)
+
+	os.Clearenv()
+}
+
+func TestS3StateStore_Errors(t *testing.T) {
+	_, server := newTestS3Server(t)
+	setTestS3StateStoreEnv(t, server.URL)
+
+	// Requests signed with another secret are rejected
+	err := os.Setenv(awsSecretAccessKeyEnvName, "another-secret")
+	require.NoError(t, err)
+
+	store, err := getStateStore()
+	require.NoError(t, err)
+
+	_, err = store.getStates(context.Background())
+	assert.Error(t, err)
+
+	err = putTestState(store, "users", &checkState{Status: successStatusMetricStatusLabelValue})
+	assert.Error(t, err)
+
+	os.Clearenv()
+
+	for _, unsetEnvName := range []string{stateS3BucketEnvName, awsRegionEnvName} {
+		setTestS3StateStoreEnv(t, server.URL)
+
+		err = os.Unsetenv(unsetEnvName)
+		require.NoError(t, err)
+
+		_, err = getStateStore()
+		assert.Error(t, err, unsetEnvName)
+
+		os.Clearenv()
+	}
+}
+
+func TestGetS3StateStore_URL(t *testing.T) {
+	setTestS3StateStoreEnv(t, "")
+
+	store, err := getS3StateStore()
+	require.NoError(t, err)
+	assert.Equal(t, "https://api-status.s3.us-east-1.amazonaws.com/states/prod.json", store.(*s3StateStore).objectURL)
+
+	err = os.Setenv(stateS3EndpointEnvName, "http://localhost:9000/")
+	require.NoError(t, err)
+
+	store, err = getS3StateStore()
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:9000/api-status/states/prod.json", store.(*s3StateStore).objectURL)
+
+	os.Clearenv()
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4ServiceEnvName          = "SIGV4_SERVICE"
+	sigV4RegionEnvName           = "SIGV4_REGION"
+	awsAccessKeyIDEnvName        = "AWS_ACCESS_KEY_ID"
+	awsSecretAccessKeyEnvName    = "AWS_SECRET_ACCESS_KEY"
+	awsSessionTokenEnvName       = "AWS_SESSION_TOKEN"
+	defaultSigV4Service          = "execute-api"
+	sigV4Algorithm               = "AWS4-HMAC-SHA256"
+	sigV4DateFormat              = "20060102"
+	sigV4TimeFormat              = "20060102T150405Z"
+	sigV4ScopeTerminator         = "aws4_request"
+	sigV4DateHeaderName          = "X-Amz-Date"
+	sigV4SecurityTokenHeaderName = "X-Amz-Security-Token"
+	// S3 signs the URL path as is, other services sign the escaped path escaped again
+	s3SigV4Service = "s3"
+)
+
+// Headers that are not signed, since clients and proxies may set or change them after the request was signed
+var sigV4UnsignedHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+}
+
+// The service (for example: execute-api for API Gateway, lambda for Lambda function URLs) and region requests are signed for
+type sigV4Config struct {
+	Service string `yaml:"service"`
+	Region  string `yaml:"region"`
+}
+
+// Signs requests with AWS Signature Version 4
+type sigV4Signer struct {
+	service string
+	region  string
+}
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// Returns a signer of the config, where the service defaults to execute-api and the region to the Lambda function's region
+func newSigV4Signer(config *sigV4Config) (*sigV4Signer, error) {
+	signer := &sigV4Signer{service: config.Service, region: config.Region}
+
+	if signer.service == "" {
+		signer.service = defaultSigV4Service
+	}
+
+	if signer.region == "" {
+		signer.region = os.Getenv(awsRegionEnvName)
+	}
+
+	if signer.region == "" {
+		return nil, fmt.Errorf("region must be set if %s is not set", awsRegionEnvName)
+	}
+
+	return signer, nil
+}
+
+// Returns the credentials from the standard environment variables, which are the execution role's credentials in a Lambda function
+func getAwsCredentials() (*awsCredentials, error) {
+	credentials := &awsCredentials{
+		accessKeyID:     os.Getenv(awsAccessKeyIDEnvName),
+		secretAccessKey: os.Getenv(awsSecretAccessKeyEnvName),
+		sessionToken:    os.Getenv(awsSessionTokenEnvName),
+	}
+
+	if credentials.accessKeyID == "" || credentials.secretAccessKey == "" {
+		return nil, fmt.Errorf("%s and %s must be set to sign requests", awsAccessKeyIDEnvName, awsSecretAccessKeyEnvName)
+	}
+
+	return credentials, nil
+}
+
+// Adds the date, the session token (if any) and the signature of the request and its body at the time to the request's headers
+func (ss *sigV4Signer) sign(request *http.Request, body []byte, credentials *awsCredentials, now time.Time) {
+	now = now.UTC()
+	request.Header.Set(sigV4DateHeaderName, now.Format(sigV4TimeFormat))
+
+	if credentials.sessionToken != "" {
+		request.Header.Set(sigV4SecurityTokenHeaderName, credentials.sessionToken)
+	}
+
+	signedHeaders := getSigV4SignedHeaders(request.Header)
+	canonicalRequest := getSigV4CanonicalRequest(request, signedHeaders, getSigV4PayloadHash(body), ss.service)
+	scope := ss.getScope(now)
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, credentials.accessKeyID, scope, strings.Join(signedHeaders, ";"),
+		ss.getSignature(credentials.secretAccessKey, now, canonicalRequest)))
+}
+
+func (ss *sigV4Signer) getScope(now time.Time) string {
+	return strings.Join([]string{now.Format(sigV4DateFormat), ss.region, ss.service, sigV4ScopeTerminator}, "/")
+}
+
+// Returns the hex signature of the canonical request, with a key derived from the secret for the date, region and service
+func (ss *sigV4Signer) getSignature(secretAccessKey string, now time.Time, canonicalRequest string) string {
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{sigV4Algorithm, now.Format(sigV4TimeFormat), ss.getScope(now), hex.EncodeToString(canonicalRequestHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, scopePart := range []string{now.Format(sigV4DateFormat), ss.region, ss.service, sigV4ScopeTerminator} {
+		key = hmacSHA256(key, scopePart)
+	}
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	hash := hmac.New(sha256.New, key)
+	_, _ = hash.Write([]byte(data))
+
+	return hash.Sum(nil)
+}
+
+func getSigV4PayloadHash(body []byte) string {
+	payloadHash := sha256.Sum256(body)
+	return hex.EncodeToString(payloadHash[:])
+}
+
+// Returns the lowercase names of the headers to sign, sorted, including the host
+func getSigV4SignedHeaders(header http.Header) []string {
+	signedHeaders := []string{"host"}
+
+	for name := range header {
+		name = strings.ToLower(name)
+		if name != "host" && !sigV4UnsignedHeaders[name] {
+			signedHeaders = append(signedHeaders, name)
+		}
+	}
+
+	sort.Strings(signedHeaders)
+
+	return signedHeaders
+}
+
+// Returns the canonical form of the request: its method, path, query, signed headers and payload hash.
+// Works for both sent and received requests, so requests can be verified by signing them again.
+func getSigV4CanonicalRequest(request *http.Request, signedHeaders []string, payloadHash string, service string) string {
+	canonicalURI := request.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+
+	if service != s3SigV4Service {
+		canonicalURI = escapeSigV4Path(canonicalURI)
+	}
+
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+
+	canonicalHeaders := make([]string, 0, len(signedHeaders))
+
+	for _, name := range signedHeaders {
+		values := request.Header.Values(name)
+		if name == "host" {
+			values = []string{host}
+		}
+
+		trimmedValues := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmedValues = append(trimmedValues, strings.Join(strings.Fields(value), " "))
+		}
+
+		canonicalHeaders = append(canonicalHeaders, name+":"+strings.Join(trimmedValues, ",")+"\n")
+	}
+
+	return strings.Join([]string{
+		request.Method,
+		canonicalURI,
+		getSigV4CanonicalQuery(request.URL.Query()),
+		strings.Join(canonicalHeaders, ""),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// Returns the query parameters escaped and sorted by name and then by value
+func getSigV4CanonicalQuery(query url.Values) string {
+	parameters := make([][2]string, 0, len(query))
+
+	for name, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, [2]string{escapeSigV4(name), escapeSigV4(value)})
+		}
+	}
+
+	sort.Slice(parameters, func(i, j int) bool {
+		if parameters[i][0] != parameters[j][0] {
+			return parameters[i][0] < parameters[j][0]
+		}
+
+		return parameters[i][1] < parameters[j][1]
+	})
+
+	encodedParameters := make([]string, 0, len(parameters))
+	for _, parameter := range parameters {
+		encodedParameters = append(encodedParameters, parameter[0]+"="+parameter[1])
+	}
+
+	return strings.Join(encodedParameters, "&")
+}
+
+// Escapes every byte but the unreserved characters (RFC 3986), where spaces are %20
+func escapeSigV4(value string) string {
+	var escaped strings.Builder
+
+	for index := 0; index < len(value); index++ {
+		character := value[index]
+
+		if 'A' <= character && character <= 'Z' || 'a' <= character && character <= 'z' || '0' <= character && character <= '9' ||
+			character == '-' || character == '_' || character == '.' || character == '~' {
+			escaped.WriteByte(character)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", character)
+		}
+	}
+
+	return escaped.String()
+}
+
+func escapeSigV4Path(path string) string {
+	segments := strings.Split(path, "/")
+	for index, segment := range segments {
+		segments[index] = escapeSigV4(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAwsAccessKeyID     = "AKIDEXAMPLE"
+	testAwsSecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// Returns whether the request's signature matches, by signing it again like AWS does
+func verifyTestSigV4Request(t *testing.T, request *http.Request, body []byte, service string, region string, secretAccessKey string) bool {
+	authorization := request.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(authorization, sigV4Algorithm+" "), authorization)
+
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(authorization, sigV4Algorithm+" "), ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+
+	now, err := time.Parse(sigV4TimeFormat, request.Header.Get(sigV4DateHeaderName))
+	require.NoError(t, err)
+
+	signer := &sigV4Signer{service: service, region: region}
+	assert.Equal(t, testAwsAccessKeyID+"/"+signer.getScope(now), fields["Credential"])
+
+	canonicalRequest := getSigV4CanonicalRequest(request, strings.Split(fields["SignedHeaders"], ";"), getSigV4PayloadHash(body), service)
+	signature := signer.getSignature(secretAccessKey, now, canonicalRequest)
+
+	return hmac.Equal([]byte(signature), []byte(fields["Signature"]))
+}
+
+// Returns a handler that responds with 403 if the request's signature does not match
+func newTestSigV4Verifier(t *testing.T, service string, region string, secretAccessKey string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		require.NoError(t, err)
+
+		if !verifyTestSigV4Request(t, request, body, service, region, secretAccessKey) {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	}
+}
+
+// The requests and signatures of the AWS Signature Version 4 test suite
+func TestSigV4Signer_Sign(t *testing.T) {
+	signer := &sigV4Signer{service: "service", region: "us-east-1"}
+	credentials := &awsCredentials{accessKeyID: testAwsAccessKeyID, secretAccessKey: testAwsSecretAccessKey}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	for expectedSignature, request := range map[string]struct {
+		method string
+		url    string
+	}{
+		"5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31": {http.MethodGet, "https://example.amazonaws.com/"},
+		"b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500": {http.MethodGet, "https://example.amazonaws.com/?Param2=value2&Param1=value1"},
+		"5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b": {http.MethodPost, "https://example.amazonaws.com/"},
+	} {
+		httpRequest, err := http.NewRequest(request.method, request.url, nil)
+		require.NoError(t, err)
+
+		signer.sign(httpRequest, nil, credentials, now)
+
+		assert.Equal(t, "20150830T123600Z", httpRequest.Header.Get(sigV4DateHeaderName))
+		assert.Equal(t, sigV4Algorithm+" Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature="+expectedSignature,
+			httpRequest.Header.Get("Authorization"), request.url)
+	}
+}
+
+func TestGetSigV4CanonicalRequest(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, "https://example.api/users/a%20b/c+d?b=2&a=2&a=1&c=x%20y", nil)
+	require.NoError(t, err)
+
+	request.Header.Set("X-Custom", "  a   b ")
+	request.Header.Set(sigV4DateHeaderName, "20150830T123600Z")
+
+	signedHeaders := getSigV4SignedHeaders(request.Header)
+	assert.Equal(t, []string{"host", "x-amz-date", "x-custom"}, signedHeaders)
+
+	assert.Equal(t, "GET\n/users/a%2520b/c%2Bd\na=1&a=2&b=2&c=x%20y\nhost:example.api\nx-amz-date:20150830T123600Z\nx-custom:a b\n\nhost;x-amz-date;x-custom\nhash",
+		getSigV4CanonicalRequest(request, signedHeaders, "hash", "execute-api"))
+	assert.True(t, strings.HasPrefix(getSigV4CanonicalRequest(request, signedHeaders, "hash", s3SigV4Service), "GET\n/users/a%20b/c+d\n"))
+}
+
+func TestGetGaugeObservers_SigV4(t *testing.T) {
+	err := os.Setenv(awsAccessKeyIDEnvName, testAwsAccessKeyID)
+	require.NoError(t, err)
+
+	err = os.Setenv(awsSecretAccessKeyEnvName, testAwsSecretAccessKey)
+	require.NoError(t, err)
+
+	err = os.Setenv(awsSessionTokenEnvName, "session-token")
+	require.NoError(t, err)
+
+	err = os.Setenv(awsRegionEnvName, "eu-west-1")
+	require.NoError(t, err)
+
+	for expectedStatus, secretAccessKey := range map[string]string{
+		successStatusMetricStatusLabelValue:           testAwsSecretAccessKey,
+		noMatchStatusCodeStatusMetricStatusLabelValue: "another-secret",
+	} {
+		server := httptest.NewServer(newTestSigV4Verifier(t, "lambda", "eu-west-1", secretAccessKey))
+
+		check, err := newApiCheck(&checkConfig{
+			Name:               "test",
+			URL:                server.URL + "/orders/a b?status=open&limit=10",
+			Method:             http.MethodPost,
+			Headers:            map[string]string{"Content-Type": "application/json"},
+			Body:               `{"id": 1}`,
+			Auth:               authConfig{SigV4: &sigV4Config{Service: "lambda"}},
+			Timeout:            "5s",
+			ExpectedStatusCode: "200",
+		})
+		require.NoError(t, err)
+
+		result := getTestCheckResult(context.Background(), t, check)
+		assert.Equal(t, expectedStatus, result.Status)
+
+		server.Close()
+	}
+
+	// Without credentials, the request can't be signed
+	err = os.Unsetenv(awsSecretAccessKeyEnvName)
+	require.NoError(t, err)
+
+	check, err := newApiCheck(&checkConfig{
+		Name:               "test",
+		URL:                "https://example.api:1234/orders",
+		Method:             http.MethodGet,
+		Auth:               authConfig{SigV4: &sigV4Config{}},
+		Timeout:            "5s",
+		ExpectedStatusCode: "200",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &sigV4Signer{service: defaultSigV4Service, region: "eu-west-1"}, check.sigV4)
+
+	result := getTestCheckResult(context.Background(), t, check)
+	assert.Equal(t, authFailedStatusMetricStatusLabelValue, result.Status)
+
+	os.Clearenv()
+}
+
+func TestNewApiCheck_BadSigV4Config(t *testing.T) {
+	for _, auth := range []authConfig{
+		{SigV4: &sigV4Config{Service: "execute-api"}},
+		{SigV4: &sigV4Config{Region: "us-east-1"}, Username: "user"},
+		{SigV4: &sigV4Config{Region: "us-east-1"}, OAuth2: &oauth2Config{TokenURL: "https://auth.example.api/oauth2/token", ClientID: "api-status"}},
+	} {
+		_, err := newApiCheck(&checkConfig{
+			Name:               "test",
+			URL:                "https://example.api:1234/orders",
+			Method:             http.MethodGet,
+			Auth:               auth,
+			Timeout:            "5s",
+			ExpectedStatusCode: "200",
+		})
+		assert.Error(t, err, auth.SigV4)
+	}
+}
+
+func TestGetEnvCheckConfig_SigV4(t *testing.T) {
+	config, err := getEnvCheckConfig()
+	require.NoError(t, err)
+	assert.Nil(t, config.Auth.SigV4)
+
+	err = os.Setenv(sigV4ServiceEnvName, "execute-api")
+	require.NoError(t, err)
+
+	err = os.Setenv(sigV4RegionEnvName, "us-east-1")
+	require.NoError(t, err)
+
+	config, err = getEnvCheckConfig()
+	require.NoError(t, err)
+	assert.Equal(t, &sigV4Config{Service: "execute-api", Region: "us-east-1"}, config.Auth.SigV4)
+
+	os.Clearenv()
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	stateStoreEnvName                = "STATE_STORE"
+	stateFileEnvName                 = "STATE_FILE"
+	fileStateStoreType               = "file"
+	memoryStateStoreType             = "memory"
+	defaultStateFileName             = "api-status-state.json"
+	consecutiveFailuresMetricName    = meterName + "_consecutive_failures"
+	statusChangedMetricName          = meterName + "_status_changed"
+	timeInStatusMetricName           = meterName + "_time_in_status"
+	previousStatusLabelName          = "previous_status"
+	timeInStatusMetricUnitLabelValue = "seconds"
+)
+
+// The state store types, by the value of STATE_STORE.
+// Other backends (for example: DynamoDB) implement stateStore and are added here.
+var stateStoreFactories = map[string]func() (stateStore, error){
+	// The default file is in the temporary directory, which is the only writable directory of a Lambda function, but is
+	// lost with the function's instance. In a Lambda function, only a file set by STATE_FILE (for example: on a mounted
+	// EFS file system) is persistent.
+	fileStateStoreType: func() (stateStore, error) {
+		path := os.Getenv(stateFileEnvName)
+		if path == "" {
+			return newFileStateStore(filepath.Join(os.TempDir(), defaultStateFileName), !isLambdaFunction()), nil
+		}
+
+		return newFileStateStore(path, true), nil
+	},
+	// The states are kept for the lifetime of the process, which includes warm invocations of a Lambda function
+	memoryStateStoreType: func() (stateStore, error) {
+		return processStateStore, nil
+	},
+	s3StateStoreType: getS3StateStore,
+}
+
+var processStateStore = newMemoryStateStore()
+
+// The last status of a check, kept between runs
+type checkState struct {
+	Status string `json:"status"`
+	// When the check got its current status
+	Since               time.Time `json:"since"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Keeps the last state of each check by its name. Implementations must be safe for concurrent use.
+type stateStore interface {
+	// Returns the states by check name, without the checks that have no state
+	getStates(ctx context.Context) (map[string]*checkState, error)
+	// Reads the states once, lets the update change them in place and writes them once. The update is called again with
+	// the states read again if the store found that another writer changed them in the meantime.
+	updateStates(ctx context.Context, update func(states map[string]*checkState)) error
+	// Whether the states outlive the process, so a Lambda function keeps them across cold starts and instances
+	persistent() bool
+}
+
+// The check's state before and after a run
+type checkStateTransition struct {
+	previous *checkState
+	current  *checkState
+}
+
+// Keeps the states in a JSON file, for daemon mode (or a Lambda function with a mounted file system)
+type fileStateStore struct {
+	path         string
+	isPersistent bool
+	lock         sync.Mutex
+}
+
+// Keeps the states in memory, also used as a stand-in for other stores in tests
+type memoryStateStore struct {
+	lock   sync.Mutex
+	states map[string]checkState
+}
+
+// Returns nil if the state store type is not set, since tracking the checks states is optional
+func getStateStore() (stateStore, error) {
+	storeType := os.Getenv(stateStoreEnvName)
+	if storeType == "" {
+		return nil, nil
+	}
+
+	newStateStore, ok := stateStoreFactories[storeType]
+	if !ok {
+		storeTypes := make([]string, 0, len(stateStoreFactories))
+		for supportedType := range stateStoreFactories {
+			storeTypes = append(storeTypes, supportedType)
+		}
+
+		sort.Strings(storeTypes)
+
+		return nil, fmt.Errorf("%s must be one of %s", stateStoreEnvName, strings.Join(storeTypes, ", "))
+	}
+
+	return newStateStore()
+}
+
+func newFileStateStore(path string, isPersistent bool) *fileStateStore {
+	return &fileStateStore{path: path, isPersistent: isPersistent}
+}
+
+func (fss *fileStateStore) persistent() bool {
+	return fss.isPersistent
+}
+
+func (fss *fileStateStore) getStates(_ context.Context) (map[string]*checkState, error) {
+	fss.lock.Lock()
+	defer fss.lock.Unlock()
+
+	return fss.readStates()
+}
+
+func (fss *fileStateStore) updateStates(_ context.Context, update func(states map[string]*checkState)) error {
+	fss.lock.Lock()
+	defer fss.lock.Unlock()
+
+	states, err := fss.readStates()
+	if err != nil {
+		return err
+	}
+
+	update(states)
+
+	return fss.writeStates(states)
+}
+
+// Returns no states if the file does not exist yet
+func (fss *fileStateStore) readStates() (map[string]*checkState, error) {
+	states := make(map[string]*checkState)
+
+	stateBytes, err := os.ReadFile(fss.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return states, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file %s: %v", fss.path, err)
+	}
+
+	if err = json.Unmarshal(stateBytes, &states); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %v", fss.path, err)
+	}
+
+	return states, nil
+}
+
+// Writes a temporary file and renames it, so the file is never left partially written
+func (fss *fileStateStore) writeStates(states map[string]*checkState) error {
+	stateBytes, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(fss.path), filepath.Base(fss.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error creating state file: %v", err)
+	}
+
+	_, err = tempFile.Write(stateBytes)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tempFile.Name(), fss.path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+		return fmt.Errorf("error writing state file %s: %v", fss.path, err)
+	}
+
+	return nil
+}
+
+func newMemoryStateStore() *memoryStateStore {
+	return &memoryStateStore{states: make(map[string]checkState)}
+}
+
+// Returns copies of the states, so they are changed only by updates
+func (mss *memoryStateStore) getStates(_ context.Context) (map[string]*checkState, error) {
+	mss.lock.Lock()
+	defer mss.lock.Unlock()
+
+	return mss.copyStates(), nil
+}
+
+func (mss *memoryStateStore) updateStates(_ context.Context, update func(states map[string]*checkState)) error {
+	mss.lock.Lock()
+	defer mss.lock.Unlock()
+
+	states := mss.copyStates()
+	update(states)
+
+	mss.states = make(map[string]checkState, len(states))
+	for checkName, state := range states {
+		mss.states[checkName] = *state
+	}
+
+	return nil
+}
+
+func (mss *memoryStateStore) copyStates() map[string]*checkState {
+	states := make(map[string]*checkState, len(mss.states))
+
+	for checkName, state := range mss.states {
+		state := state
+		states[checkName] = &state
+	}
+
+	return states
+}
+
+// The states are lost with the process, which in a Lambda function is replaced on cold starts
+func (mss *memoryStateStore) persistent() bool {
+	return false
+}
+
+// Returns the check's state after a run at the time with the status, where any status but success is a failure
+func getNextCheckState(previous *checkState, status string, now time.Time) *checkState {
+	state := &checkState{Status: status, Since: now, UpdatedAt: now}
+
+	if previous != nil && previous.Status == status {
+		state.Since = previous.Since
+	}
+
+	if status != successStatusMetricStatusLabelValue {
+		state.ConsecutiveFailures = 1
+		if previous != nil {
+			state.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+		}
+	}
+
+	return state
+}
+
+func (cst *checkStateTransition) statusChanged() bool {
+	return cst.previous != nil && cst.previous.Status != cst.current.Status
+}
+
+// Records the statuses of the checks' runs in one update of the state store, and returns the state gauge observers of
+// the checks that ran, which are also added to their results, and the checks' state transitions. If the states could
+// not be recorded, there are no state gauge observers and the transitions are nil.
+func (las *logzioApiStatus) updateChecksStates(checks []*apiCheck, checkResults []*checkResult, checksRuns []*checkRun) ([]metricRegister, []*checkStateTransition) {
+	gaugeObservers := make([]metricRegister, 0)
+	transitions := make([]*checkStateTransition, len(checks))
+
+	err := las.stateStore.updateStates(las.ctx, func(states map[string]*checkState) {
+		for index, check := range checks {
+			previous := states[check.name]
+			transitions[index] = &checkStateTransition{previous: previous, current: getNextCheckState(previous, checkResults[index].Status, checksRuns[index].start)}
+			states[check.name] = transitions[index].current
+		}
+	})
+	if err != nil {
+		errorLogger.Printf("Error updating checks states: %v\n", err)
+		return gaugeObservers, make([]*checkStateTransition, len(checks))
+	}
+
+	for index, check := range checks {
+		if checksRuns[index].err == nil {
+			stateGaugeObservers := check.getStateGaugeObservers(transitions[index])
+			checkResults[index].addMetrics(stateGaugeObservers)
+			gaugeObservers = append(gaugeObservers, stateGaugeObservers...)
+		}
+	}
+
+	return gaugeObservers, transitions
+}
+
+// Returns gauge observers of the consecutive failures, whether the status changed and the seconds in the current status
+func (ac *apiCheck) getStateGaugeObservers(transition *checkStateTransition) []metricRegister {
+	current := transition.current
+
+	var statusChanged int64
+	statusChangedAttributes := []attribute.KeyValue{
+		attribute.String(checkNameLabelName, ac.name),
+		attribute.String(urlLabelName, ac.getRedactedURL()),
+		attribute.String(methodLabelName, ac.method),
+		attribute.String(statusMetricStatusLabelName, current.Status),
+	}
+
+	if transition.previous != nil {
+		statusChangedAttributes = append(statusChangedAttributes, attribute.String(previousStatusLabelName, transition.previous.Status))
+	}
+
+	if transition.statusChanged() {
+		statusChanged = 1
+	}
+
+	return []metricRegister{
+		newInt64GaugeObserver(consecutiveFailuresMetricName, int64(current.ConsecutiveFailures), []attribute.KeyValue{
+			attribute.String(checkNameLabelName, ac.name),
+			attribute.String(urlLabelName, ac.getRedactedURL()),
+			attribute.String(methodLabelName, ac.method),
+		}, "API check consecutive failures"),
+		newInt64GaugeObserver(statusChangedMetricName, statusChanged, statusChangedAttributes, "API check status changed since the last run"),
+		newFloat64GaugeObserver(timeInStatusMetricName, current.UpdatedAt.Sub(current.Since).Seconds(), []attribute.KeyValue{
+			attribute.String(checkNameLabelName, ac.name),
+			attribute.String(urlLabelName, ac.getRedactedURL()),
+			attribute.String(methodLabelName, ac.method),
+			attribute.String(statusMetricStatusLabelName, current.Status),
+			attribute.String(unitLabelName, timeInStatusMetricUnitLabelValue),
+		}, "API check time in its current status"),
+	}
+}
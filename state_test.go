@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunChecks_States(t *testing.T) {
+	err := os.Setenv(checksEnvName, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+	require.NoError(t, err)
+
+	apiStatus, err := newCheckCommandApiStatus("")
+	require.NoError(t, err)
+	os.Clearenv()
+
+	apiStatus.stateStore = newMemoryStateStore()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	runChecks := func(statusCode int) *checkResult {
+		httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+			httpmock.NewStringResponder(statusCode, ""))
+
+		_, checkResults, err := apiStatus.runChecks()
+		require.NoError(t, err)
+		require.Len(t, checkResults, 1)
+
+		return checkResults[0]
+	}
+
+	// The first run has no previous status
+	result := runChecks(http.StatusOK)
+	assert.Equal(t, float64(0), result.metrics[consecutiveFailuresMetricName])
+	assert.Equal(t, float64(0), result.metrics[statusChangedMetricName])
+	assert.Equal(t, float64(0), result.metrics[timeInStatusMetricName])
+
+	result = runChecks(http.StatusInternalServerError)
+	assert.Equal(t, float64(1), result.metrics[consecutiveFailuresMetricName])
+	assert.Equal(t, float64(1), result.metrics[statusChangedMetricName])
+	assert.Equal(t, float64(0), result.metrics[timeInStatusMetricName])
+
+	for _, record := range result.Metrics {
+		if record.Name == statusChangedMetricName {
+			assert.Equal(t, map[string]string{statusMetricStatusLabelName: noMatchStatusCodeStatusMetricStatusLabelValue, previousStatusLabelName: successStatusMetricStatusLabelValue}, record.Labels)
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	result = runChecks(http.StatusInternalServerError)
+	assert.Equal(t, float64(2), result.metrics[consecutiveFailuresMetricName])
+	assert.Equal(t, float64(0), result.metrics[statusChangedMetricName])
+	assert.GreaterOrEqual(t, result.metrics[timeInStatusMetricName], 0.01)
+
+	result = runChecks(http.StatusOK)
+	assert.Equal(t, float64(0), result.metrics[consecutiveFailuresMetricName])
+	assert.Equal(t, float64(1), result.metrics[statusChangedMetricName])
+
+	states, err := apiStatus.stateStore.getStates(context.Background())
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+
+	state := states["users"]
+	assert.Equal(t, successStatusMetricStatusLabelValue, state.Status)
+	assert.Equal(t, state.Since, state.UpdatedAt)
+}
+
+func TestRunChecks_NoStateStore(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://example.api:1234/users",
+		httpmock.NewStringResponder(http.StatusOK, ""))
+
+	checkResults := getTestRetryCheckResults(t, `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`)
+	require.Len(t, checkResults, 1)
+
+	assert.NotContains(t, checkResults[0].metrics, consecutiveFailuresMetricName)
+	assert.NotContains(t, checkResults[0].metrics, statusChangedMetricName)
+	assert.NotContains(t, checkResults[0].metrics, timeInStatusMetricName)
+}
+
+func TestGetNextCheckState(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour)
+
+	state := getNextCheckState(nil, noMatchStatusCodeStatusMetricStatusLabelValue, now)
+	assert.Equal(t, &checkState{Status: noMatchStatusCodeStatusMetricStatusLabelValue, Since: now, ConsecutiveFailures: 1, UpdatedAt: now}, state)
+
+	// Any status but success is a failure, but a different failure status starts a new status
+	previous := &checkState{Status: responseTimeoutStatusMetricStatusLabelValue, Since: start, ConsecutiveFailures: 3, UpdatedAt: start}
+
+	state = getNextCheckState(previous, responseTimeoutStatusMetricStatusLabelValue, now)
+	assert.Equal(t, &checkState{Status: responseTimeoutStatusMetricStatusLabelValue, Since: start, ConsecutiveFailures: 4, UpdatedAt: now}, state)
+
+	state = getNextCheckState(previous, noMatchStatusCodeStatusMetricStatusLabelValue, now)
+	assert.Equal(t, &checkState{Status: noMatchStatusCodeStatusMetricStatusLabelValue, Since: now, ConsecutiveFailures: 4, UpdatedAt: now}, state)
+
+	state = getNextCheckState(previous, successStatusMetricStatusLabelValue, now)
+	assert.Equal(t, &checkState{Status: successStatusMetricStatusLabelValue, Since: now, ConsecutiveFailures: 0, UpdatedAt: now}, state)
+}
+
+// Sets the check's state in one update of the store
+func putTestState(store stateStore, checkName string, state *checkState) error {
+	return store.updateStates(context.Background(), func(states map[string]*checkState) {
+		states[checkName] = state
+	})
+}
+
+func TestFileStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := newFileStateStore(path, true)
+
+	states, err := store.getStates(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, states)
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	usersState := &checkState{Status: responseTimeoutStatusMetricStatusLabelValue, Since: since, ConsecutiveFailures: 2, UpdatedAt: since.Add(time.Minute)}
+
+	err = putTestState(store, "users", usersState)
+	require.NoError(t, err)
+
+	err = putTestState(store, "orders", &checkState{Status: successStatusMetricStatusLabelValue, Since: since, UpdatedAt: since})
+	require.NoError(t, err)
+
+	// The states are kept across processes
+	states, err = newFileStateStore(path, true).getStates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, usersState, states["users"])
+	assert.Len(t, states, 2)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	err = os.WriteFile(path, []byte("{"), 0600)
+	require.NoError(t, err)
+
+	_, err = store.getStates(context.Background())
+	assert.Error(t, err)
+
+	err = putTestState(store, "users", usersState)
+	assert.Error(t, err)
+}
+
+func TestGetStateStore(t *testing.T) {
+	store, err := getStateStore()
+	require.NoError(t, err)
+	assert.Nil(t, store)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	err = os.Setenv(stateStoreEnvName, fileStateStoreType)
+	require.NoError(t, err)
+
+	err = os.Setenv(stateFileEnvName, path)
+	require.NoError(t, err)
+
+	store, err = getStateStore()
+	require.NoError(t, err)
+	assert.Equal(t, newFileStateStore(path, true), store)
+
+	// The default file is in the writable temporary directory, which a Lambda function loses on cold starts
+	err = os.Unsetenv(stateFileEnvName)
+	require.NoError(t, err)
+
+	defaultPath := filepath.Join(os.TempDir(), defaultStateFileName)
+
+	store, err = getStateStore()
+	require.NoError(t, err)
+	assert.Equal(t, newFileStateStore(defaultPath, true), store)
+
+	err = os.Setenv(awsLambdaFunctionNameEnvName, "api-status")
+	require.NoError(t, err)
+
+	store, err = getStateStore()
+	require.NoError(t, err)
+	assert.Equal(t, newFileStateStore(defaultPath, false), store)
+	assert.False(t, store.persistent())
+
+	err = os.Setenv(stateStoreEnvName, memoryStateStoreType)
+	require.NoError(t, err)
+
+	store, err = getStateStore()
+	require.NoError(t, err)
+	assert.Same(t, processStateStore, store)
+	assert.False(t, store.persistent())
+
+	err = os.Setenv(stateStoreEnvName, "dynamodb")
+	require.NoError(t, err)
+
+	_, err = getStateStore()
+	assert.EqualError(t, err, "STATE_STORE must be one of file, memory, s3")
+
+	os.Clearenv()
+}
+
+func TestNewLogzioApiStatus_NonPersistentStateStore(t *testing.T) {
+	_, server := newTestS3Server(t)
+
+	for _, testCase := range []struct {
+		storeType       string
+		lambdaFunction  string
+		expectedWarning bool
+	}{
+		{memoryStateStoreType, "api-status", true},
+		{fileStateStoreType, "api-status", true},
+		{s3StateStoreType, "api-status", false},
+		{memoryStateStoreType, "", false},
+	} {
+		var output bytes.Buffer
+
+		err := setLoggers(&output)
+		require.NoError(t, err)
+
+		setTestS3StateStoreEnv(t, server.URL)
+
+		for name, value := range map[string]string{
+			checksEnvName:                `{"checks": [{"name": "users", "url": "https://example.api:1234/users"}]}`,
+			metricsExportersEnvName:      stdoutMetricsExporterName,
+			stateStoreEnvName:            testCase.storeType,
+			awsLambdaFunctionNameEnvName: testCase.lambdaFunction,
+		} {
+			err = os.Setenv(name, value)
+			require.NoError(t, err)
+		}
+
+		_, err = newLogzioApiStatus(context.Background(), lambdaMode)
+		require.NoError(t, err)
+
+		warnings := 0
+		for _, record := range getLogRecords(t, &output) {
+			if record["level"] == "WARN" {
+				warnings++
+			}
+		}
+
+		assert.Equal(t, testCase.expectedWarning, warnings == 1, testCase)
+
+		os.Clearenv()
+	}
+
+	err := setLoggers(os.Stdout)
+	require.NoError(t, err)
+}
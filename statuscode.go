@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	minStatusCode = 100
+	maxStatusCode = 599
+)
+
+// A set of status codes, for example: 200,204 or 2xx or 200-299 or !5xx.
+// Negated terms exclude status codes. Without other terms, every other status code is allowed.
+type statusCodeExpression struct {
+	expression     string
+	includedRanges []statusCodeRange
+	excludedRanges []statusCodeRange
+}
+
+type statusCodeRange struct {
+	from int
+	to   int
+}
+
+func newStatusCodeExpression(expression string) (*statusCodeExpression, error) {
+	statusCodeExp := &statusCodeExpression{
+		expression: strings.ReplaceAll(expression, " ", ""),
+	}
+
+	if statusCodeExp.expression == "" {
+		return nil, fmt.Errorf("status code expression must not be empty")
+	}
+
+	for _, term := range strings.Split(statusCodeExp.expression, ",") {
+		negated := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+
+		codeRange, err := parseStatusCodeRange(term)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing status code expression %s: %v", expression, err)
+		}
+
+		if negated {
+			statusCodeExp.excludedRanges = append(statusCodeExp.excludedRanges, codeRange)
+		} else {
+			statusCodeExp.includedRanges = append(statusCodeExp.includedRanges, codeRange)
+		}
+	}
+
+	return statusCodeExp, nil
+}
+
+// Parses a status code (200), a status code class (2xx) or a status code range (200-299)
+func parseStatusCodeRange(term string) (statusCodeRange, error) {
+	if len(term) == 3 && strings.ToLower(term[1:]) == "xx" {
+		class, err := strconv.Atoi(term[:1])
+		if err != nil || class < minStatusCode/100 || class > maxStatusCode/100 {
+			return statusCodeRange{}, fmt.Errorf("%s is not a valid status code class", term)
+		}
+
+		return statusCodeRange{from: class * 100, to: class*100 + 99}, nil
+	}
+
+	from, to := term, term
+	if strings.Contains(term, "-") {
+		bounds := strings.SplitN(term, "-", 2)
+		from, to = bounds[0], bounds[1]
+	}
+
+	fromStatusCode, err := parseStatusCode(from)
+	if err != nil {
+		return statusCodeRange{}, err
+	}
+
+	toStatusCode, err := parseStatusCode(to)
+	if err != nil {
+		return statusCodeRange{}, err
+	}
+
+	if fromStatusCode > toStatusCode {
+		return statusCodeRange{}, fmt.Errorf("status code range %s must be ascending", term)
+	}
+
+	return statusCodeRange{from: fromStatusCode, to: toStatusCode}, nil
+}
+
+func parseStatusCode(statusCodeString string) (int, error) {
+	statusCode, err := strconv.Atoi(statusCodeString)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number", statusCodeString)
+	}
+
+	if statusCode < minStatusCode || statusCode > maxStatusCode {
+		return 0, fmt.Errorf("status code %d must be between %d and %d (inclusive)", statusCode, minStatusCode, maxStatusCode)
+	}
+
+	return statusCode, nil
+}
+
+func (sce *statusCodeExpression) matches(statusCode int) bool {
+	for _, codeRange := range sce.excludedRanges {
+		if codeRange.contains(statusCode) {
+			return false
+		}
+	}
+
+	if len(sce.includedRanges) == 0 {
+		return true
+	}
+
+	for _, codeRange := range sce.includedRanges {
+		if codeRange.contains(statusCode) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (scr statusCodeRange) contains(statusCode int) bool {
+	return statusCode >= scr.from && statusCode <= scr.to
+}
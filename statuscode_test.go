@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusCodeExpression_Matches(t *testing.T) {
+	expressions := map[string]map[int]bool{
+		"200":            {200: true, 204: false},
+		"200,204":        {200: true, 204: true, 201: false},
+		"2xx":            {200: true, 299: true, 301: false},
+		"2XX":            {250: true, 199: false},
+		"200-299":        {200: true, 299: true, 300: false},
+		"!5xx":           {200: true, 404: true, 500: false, 503: false},
+		"2xx,!204":       {200: true, 204: false, 302: false},
+		"200, 301 - 302": {200: true, 301: true, 302: true, 303: false},
+	}
+
+	for expression, statusCodes := range expressions {
+		statusCodeExp, err := newStatusCodeExpression(expression)
+		require.NoError(t, err, expression)
+
+		for statusCode, expected := range statusCodes {
+			assert.Equal(t, expected, statusCodeExp.matches(statusCode), "%s %d", expression, statusCode)
+		}
+	}
+}
+
+func TestNewStatusCodeExpression_BadExpression(t *testing.T) {
+	for _, expression := range []string{"", "abc", "25", "600", "6xx", "0xx", "299-200", "200,", "!", "2x"} {
+		_, err := newStatusCodeExpression(expression)
+		assert.Error(t, err, expression)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timestamps of the phases of an HTTP request, collected with httptrace. When redirects are followed, the last request is measured.
+// Phases that did not happen (for example: DNS lookup of an IP address, or connecting on a reused connection) stay zero.
+type httpTimings struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	bodyDone     time.Time
+	lock         sync.Mutex
+}
+
+func (ht *httpTimings) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(_ string) {
+			ht.reset()
+		},
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			ht.setNow(&ht.dnsStart)
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			ht.setNow(&ht.dnsDone)
+		},
+		ConnectStart: func(_, _ string) {
+			// Happy eyeballs may dial more than once, measure from the first dial
+			ht.setNow(&ht.connectStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				ht.setNow(&ht.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() {
+			ht.setNow(&ht.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				ht.setNow(&ht.tlsDone)
+			}
+		},
+		GotFirstResponseByte: func() {
+			ht.setNow(&ht.firstByte)
+		},
+	}
+}
+
+func (ht *httpTimings) reset() {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	ht.start = time.Now()
+	ht.dnsStart, ht.dnsDone = time.Time{}, time.Time{}
+	ht.connectStart, ht.connectDone = time.Time{}, time.Time{}
+	ht.tlsStart, ht.tlsDone = time.Time{}, time.Time{}
+	ht.firstByte, ht.bodyDone = time.Time{}, time.Time{}
+}
+
+// Sets the timestamp to now, unless it was already set.
+// Dial and trace hooks may run on other goroutines, after the request has finished.
+func (ht *httpTimings) setNow(timestamp *time.Time) {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	if timestamp.IsZero() {
+		*timestamp = time.Now()
+	}
+}
+
+func (ht *httpTimings) dnsLookupTime() (float64, bool) {
+	return ht.getPhaseTime(&ht.dnsStart, &ht.dnsDone)
+}
+
+func (ht *httpTimings) connectTime() (float64, bool) {
+	return ht.getPhaseTime(&ht.connectStart, &ht.connectDone)
+}
+
+func (ht *httpTimings) tlsHandshakeTime() (float64, bool) {
+	return ht.getPhaseTime(&ht.tlsStart, &ht.tlsDone)
+}
+
+func (ht *httpTimings) timeToFirstByte() (float64, bool) {
+	return ht.getPhaseTime(&ht.start, &ht.firstByte)
+}
+
+func (ht *httpTimings) bodyDownloadTime() (float64, bool) {
+	return ht.getPhaseTime(&ht.firstByte, &ht.bodyDone)
+}
+
+// Returns the phase time in milliseconds, and whether the phase happened
+func (ht *httpTimings) getPhaseTime(start *time.Time, end *time.Time) (float64, bool) {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	if start.IsZero() || end.IsZero() {
+		return 0, false
+	}
+
+	return float64(end.Sub(*start)) / float64(time.Millisecond), true
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGaugeObservers_Timings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte("success"))
+	}))
+	defer server.Close()
+
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
+		url:                        server.URL,
+		method:                     http.MethodGet,
+		responseTimeout:            10 * time.Second,
+		expectedResponseStatusCode: expectedResponseStatusCode,
+	}
+
+	gaugeObservers, _, err := check.getGaugeObservers(context.Background())
+	require.NoError(t, err)
+
+	metricNames := make([]string, 0)
+
+	for _, gaugeObserver := range gaugeObservers {
+		switch typedGaugeObserver := gaugeObserver.(type) {
+		case *int64GaugeObserver:
+			metricNames = append(metricNames, typedGaugeObserver.name)
+		case *float64GaugeObserver:
+			metricNames = append(metricNames, typedGaugeObserver.name)
+		}
+	}
+
+	// The server is reached by IP address over plain HTTP, so there is no DNS lookup or TLS handshake
+	assert.ElementsMatch(t, []string{
+		responseTimeMetricName,
+		connectTimeMetricName,
+		timeToFirstByteMetricName,
+		bodyDownloadTimeMetricName,
+		responseBodyLengthMetricName,
+		statusMetricName,
+	}, metricNames)
+}
+
+func TestHttpTimings_PhaseTimes(t *testing.T) {
+	start := time.Now()
+	timings := &httpTimings{
+		start:        start,
+		connectStart: start,
+		connectDone:  start.Add(5 * time.Millisecond),
+		firstByte:    start.Add(20 * time.Millisecond),
+		bodyDone:     start.Add(30 * time.Millisecond),
+	}
+
+	connectTime, ok := timings.connectTime()
+	assert.True(t, ok)
+	assert.Equal(t, float64(5), connectTime)
+
+	timeToFirstByte, ok := timings.timeToFirstByte()
+	assert.True(t, ok)
+	assert.Equal(t, float64(20), timeToFirstByte)
+
+	bodyDownloadTime, ok := timings.bodyDownloadTime()
+	assert.True(t, ok)
+	assert.Equal(t, float64(10), bodyDownloadTime)
+
+	_, ok = timings.dnsLookupTime()
+	assert.False(t, ok)
+
+	_, ok = timings.tlsHandshakeTime()
+	assert.False(t, ok)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	tlsClientCertEnvName         = "TLS_CLIENT_CERT"
+	tlsClientCertFileEnvName     = "TLS_CLIENT_CERT_FILE"
+	tlsClientKeyEnvName          = "TLS_CLIENT_KEY"
+	tlsClientKeyFileEnvName      = "TLS_CLIENT_KEY_FILE"
+	tlsCABundleEnvName           = "TLS_CA_BUNDLE"
+	tlsCABundleFileEnvName       = "TLS_CA_BUNDLE_FILE"
+	tlsInsecureSkipVerifyEnvName = "TLS_INSECURE_SKIP_VERIFY"
+	tlsServerNameEnvName         = "TLS_SERVER_NAME"
+	tlsMinVersionEnvName         = "TLS_MIN_VERSION"
+)
+
+// The minimum TLS versions, by their value in the config
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// The TLS settings of a check's connections, where PEM values are set inline or by a file path
+type tlsConfig struct {
+	ClientCert     string `yaml:"client_cert"`
+	ClientCertFile string `yaml:"client_cert_file"`
+	ClientKey      string `yaml:"client_key"`
+	ClientKeyFile  string `yaml:"client_key_file"`
+	// Trusted instead of the system's root CAs
+	CABundle     string `yaml:"ca_bundle"`
+	CABundleFile string `yaml:"ca_bundle_file"`
+	// Skips verifying the API's certificate, so it must be set explicitly
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	ServerName         string `yaml:"server_name"`
+	MinVersion         string `yaml:"min_version"`
+}
+
+// Returns the transport of the check's requests, which is the default transport unless the check has its own connection settings
+func (ac *apiCheck) getTransport() http.RoundTripper {
+	if ac.transport != nil {
+		return ac.transport
+	}
+
+	return http.DefaultTransport
+}
+
+// Creates the check's own transport if it has connection settings that are different from the default transport.
+// The transport is kept for the check's next runs, so they can reuse its connections.
+func (ac *apiCheck) setTransport(config tlsConfig) error {
+	clientTLSConfig, err := newClientTLSConfig(config)
+	if err != nil {
+		return err
+	}
+
+	if ac.connectTimeout == 0 && ac.tlsHandshakeTimeout == 0 && clientTLSConfig == nil {
+		return nil
+	}
+
+	// Cloned when the check is created, so it has the default transport's current settings
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("connection settings need the default transport to be an *http.Transport, not %T", http.DefaultTransport)
+	}
+
+	transport := defaultTransport.Clone()
+
+	if ac.connectTimeout != 0 {
+		dialer := &net.Dialer{Timeout: ac.connectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+
+	if ac.tlsHandshakeTimeout != 0 {
+		transport.TLSHandshakeTimeout = ac.tlsHandshakeTimeout
+	}
+
+	if clientTLSConfig != nil {
+		transport.TLSClientConfig = clientTLSConfig
+	}
+
+	ac.transport = transport
+
+	return nil
+}
+
+// Returns the TLS config of the settings, or nil if none of them is set
+func newClientTLSConfig(config tlsConfig) (*tls.Config, error) {
+	if config == (tlsConfig{}) {
+		return nil, nil
+	}
+
+	clientTLSConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		ServerName:         config.ServerName,
+	}
+
+	if config.MinVersion != "" {
+		minVersion, ok := tlsVersions[config.MinVersion]
+		if !ok {
+			versions := make([]string, 0, len(tlsVersions))
+			for version := range tlsVersions {
+				versions = append(versions, version)
+			}
+
+			sort.Strings(versions)
+
+			return nil, fmt.Errorf("TLS min version %s must be one of %s", config.MinVersion, strings.Join(versions, ", "))
+		}
+
+		clientTLSConfig.MinVersion = minVersion
+	}
+
+	clientCert, err := getPEM(config.ClientCert, config.ClientCertFile, "client certificate")
+	if err != nil {
+		return nil, err
+	}
+
+	clientKey, err := getPEM(config.ClientKey, config.ClientKeyFile, "client key")
+	if err != nil {
+		return nil, err
+	}
+
+	if (clientCert == nil) != (clientKey == nil) {
+		return nil, fmt.Errorf("client certificate and client key must be set together")
+	}
+
+	if clientCert != nil {
+		certificate, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing client certificate and key: %v", err)
+		}
+
+		clientTLSConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	caBundle, err := getPEM(config.CABundle, config.CABundleFile, "CA bundle")
+	if err != nil {
+		return nil, err
+	}
+
+	if caBundle != nil {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("CA bundle must contain at least one PEM certificate")
+		}
+
+		clientTLSConfig.RootCAs = rootCAs
+	}
+
+	return clientTLSConfig, nil
+}
+
+// Returns the inline PEM or the content of its file, or nil if neither is set
+func getPEM(inline string, file string, description string) ([]byte, error) {
+	if inline != "" && file != "" {
+		return nil, fmt.Errorf("only one of %s and %s file can be set", description, description)
+	}
+
+	if file != "" {
+		pemBytes, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s file %s: %v", description, file, err)
+		}
+
+		return pemBytes, nil
+	}
+
+	if inline != "" {
+		return []byte(inline), nil
+	}
+
+	return nil, nil
+}
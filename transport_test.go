@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestCheckResult(ctx context.Context, t *testing.T, check *apiCheck) *checkResult {
+	run := check.run(ctx)
+	require.NoError(t, run.err)
+
+	return run.result
+}
+
+func TestSetTransport(t *testing.T) {
+	check := &apiCheck{name: "test", url: "https://example.api:1234", method: http.MethodGet}
+	err := check.setTransport(tlsConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, check.transport)
+	assert.Equal(t, http.DefaultTransport, check.getTransport())
+
+	check.tlsHandshakeTimeout = time.Second
+	err = check.setTransport(tlsConfig{})
+	require.NoError(t, err)
+	require.IsType(t, &http.Transport{}, check.transport)
+	assert.Equal(t, time.Second, check.transport.(*http.Transport).TLSHandshakeTimeout)
+	assert.Equal(t, check.transport, check.getTransport())
+
+	// The check's transport is cloned from the default transport it was created with
+	defaultTransport := http.DefaultTransport
+	defer func() {
+		http.DefaultTransport = defaultTransport
+	}()
+
+	replacedTransport := defaultTransport.(*http.Transport).Clone()
+	replacedTransport.MaxIdleConnsPerHost = 7
+	http.DefaultTransport = replacedTransport
+
+	err = check.setTransport(tlsConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, 7, check.transport.(*http.Transport).MaxIdleConnsPerHost)
+
+	http.DefaultTransport = http.NewFileTransport(http.Dir(t.TempDir()))
+
+	err = check.setTransport(tlsConfig{})
+	assert.Error(t, err)
+}
+
+func TestGetGaugeObservers_SubSecondTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-request.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
+		url:                        server.URL,
+		method:                     http.MethodGet,
+		responseTimeout:            200 * time.Millisecond,
+		expectedResponseStatusCode: expectedResponseStatusCode,
+	}
+
+	start := time.Now()
+	result := getTestCheckResult(context.Background(), t, check)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	assert.Equal(t, responseTimeoutStatusMetricStatusLabelValue, result.Status)
+	assert.Equal(t, "0.2", result.Labels[statusMetricResponseTimeoutLabelName])
+	assert.Equal(t, responseTimeoutPhaseLabelValue, result.Labels[statusMetricTimeoutPhaseLabelName])
+}
+
+func TestGetGaugeObservers_LambdaDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-request.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
+		url:                        server.URL,
+		method:                     http.MethodGet,
+		responseTimeout:            10 * time.Second,
+		expectedResponseStatusCode: expectedResponseStatusCode,
+	}
+
+	// The request's deadline is the invocation's deadline when it is earlier than the check's timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := getTestCheckResult(ctx, t, check)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, responseTimeoutStatusMetricStatusLabelValue, result.Status)
+}
+
+func TestGetGaugeObservers_TLSHandshakeTimeout(t *testing.T) {
+	// Accepts connections but never answers the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	connections := make(chan net.Conn, 1)
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				close(connections)
+				return
+			}
+
+			connections <- connection
+		}
+	}()
+
+	defer func() {
+		_ = listener.Close()
+		for connection := range connections {
+			_ = connection.Close()
+		}
+	}()
+
+	expectedResponseStatusCode, err := newStatusCodeExpression("200")
+	require.NoError(t, err)
+
+	check := &apiCheck{
+		name:                       "test",
+		url:                        "https://" + listener.Addr().String(),
+		method:                     http.MethodGet,
+		responseTimeout:            5 * time.Second,
+		tlsHandshakeTimeout:        200 * time.Millisecond,
+		expectedResponseStatusCode: expectedResponseStatusCode,
+	}
+
+	err = check.setTransport(tlsConfig{})
+	require.NoError(t, err)
+
+	start := time.Now()
+	result := getTestCheckResult(context.Background(), t, check)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	assert.Equal(t, responseTimeoutStatusMetricStatusLabelValue, result.Status)
+	assert.Equal(t, "5", result.Labels[statusMetricResponseTimeoutLabelName])
+	assert.Equal(t, tlsHandshakeTimeoutPhaseLabelValue, result.Labels[statusMetricTimeoutPhaseLabelName])
+}
+
+// Returns the PEM encoding of the certificate and its key
+func encodeTestPEM(t *testing.T, certificate *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	return string(certificatePEM), string(keyPEM)
+}
+
+func TestNewClientTLSConfig(t *testing.T) {
+	clientTLSConfig, err := newClientTLSConfig(tlsConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, clientTLSConfig)
+
+	ca, caKey := createTestCertificate(t, "Test CA", "", time.Now().Add(time.Hour), nil, nil)
+	client, clientKey := createTestCertificate(t, "client", "", time.Now().Add(time.Hour), ca, caKey)
+	caPEM, _ := encodeTestPEM(t, ca, caKey)
+	clientPEM, clientKeyPEM := encodeTestPEM(t, client, clientKey)
+
+	clientKeyFile := filepath.Join(t.TempDir(), "client.key")
+	err = os.WriteFile(clientKeyFile, []byte(clientKeyPEM), 0600)
+	require.NoError(t, err)
+
+	clientTLSConfig, err = newClientTLSConfig(tlsConfig{
+		ClientCert:    clientPEM,
+		ClientKeyFile: clientKeyFile,
+		CABundle:      caPEM,
+		ServerName:    "example.api",
+		MinVersion:    "1.3",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, clientTLSConfig)
+	assert.Len(t, clientTLSConfig.Certificates, 1)
+	assert.NotNil(t, clientTLSConfig.RootCAs)
+	assert.Equal(t, "example.api", clientTLSConfig.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS13), clientTLSConfig.MinVersion)
+	assert.False(t, clientTLSConfig.InsecureSkipVerify)
+
+	for _, config := range []tlsConfig{
+		{MinVersion: "1.4"},
+		{ClientCert: clientPEM},
+		{ClientKey: clientKeyPEM},
+		{ClientCert: clientPEM, ClientKey: clientKeyPEM, ClientKeyFile: clientKeyFile},
+		{ClientCert: clientPEM, ClientKey: caPEM},
+		{ClientCertFile: filepath.Join(t.TempDir(), "missing.crt"), ClientKey: clientKeyPEM},
+		{CABundle: clientKeyPEM},
+	} {
+		_, err = newClientTLSConfig(config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestGetGaugeObservers_MutualTLS(t *testing.T) {
+	ca, caKey := createTestCertificate(t, "Test CA", "", time.Now().Add(time.Hour), nil, nil)
+	serverCertificate, serverKey := createTestCertificate(t, "example.api", "example.api", time.Now().Add(time.Hour), ca, caKey)
+	client, clientKey := createTestCertificate(t, "client", "", time.Now().Add(time.Hour), ca, caKey)
+
+	caPEM, _ := encodeTestPEM(t, ca, caKey)
+	serverPEM, serverKeyPEM := encodeTestPEM(t, serverCertificate, serverKey)
+	clientPEM, clientKeyPEM := encodeTestPEM(t, client, clientKey)
+
+	serverTLSCertificate, err := tls.X509KeyPair([]byte(serverPEM), []byte(serverKeyPEM))
+	require.NoError(t, err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	// Only answers clients with a certificate of the private CA
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverTLSCertificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caBundleFile := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caBundleFile, []byte(caPEM), 0600)
+	require.NoError(t, err)
+
+	// The server's certificate is for example.api, which is reached by its IP address with the SNI server name override
+	for expectedStatus, config := range map[string]tlsConfig{
+		successStatusMetricStatusLabelValue:          {ClientCert: clientPEM, ClientKey: clientKeyPEM, CABundleFile: caBundleFile, ServerName: "example.api", MinVersion: "1.2"},
+		connectionFailedStatusMetricStatusLabelValue: {CABundleFile: caBundleFile, ServerName: "example.api"},
+	} {
+		check, err := newApiCheck(&checkConfig{
+			Name:               "test",
+			URL:                server.URL,
+			Method:             http.MethodGet,
+			Timeout:            "5s",
+			ExpectedStatusCode: "200",
+			TLS:                config,
+		})
+		require.NoError(t, err)
+
+		result := getTestCheckResult(context.Background(), t, check)
+		assert.Equal(t, expectedStatus, result.Status)
+	}
+
+	// Without the CA bundle, the server's certificate is not trusted
+	check, err := newApiCheck(&checkConfig{
+		Name:               "test",
+		URL:                server.URL,
+		Method:             http.MethodGet,
+		Timeout:            "5s",
+		ExpectedStatusCode: "200",
+		TLS:                tlsConfig{ClientCert: clientPEM, ClientKey: clientKeyPEM, ServerName: "example.api"},
+	})
+	require.NoError(t, err)
+
+	result := getTestCheckResult(context.Background(), t, check)
+	assert.Equal(t, connectionFailedStatusMetricStatusLabelValue, result.Status)
+	assert.Equal(t, tlsErrorTypeLabelValue, result.Labels[errorTypeLabelName])
+}
+
+func TestGetGaugeObservers_InsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	for expectedStatus, insecureSkipVerify := range map[string]bool{
+		successStatusMetricStatusLabelValue:          true,
+		connectionFailedStatusMetricStatusLabelValue: false,
+	} {
+		check, err := newApiCheck(&checkConfig{
+			Name:               "test",
+			URL:                server.URL,
+			Method:             http.MethodGet,
+			Timeout:            "5s",
+			ExpectedStatusCode: "200",
+			TLS:                tlsConfig{InsecureSkipVerify: insecureSkipVerify},
+		})
+		require.NoError(t, err)
+
+		result := getTestCheckResult(context.Background(), t, check)
+		assert.Equal(t, expectedStatus, result.Status)
+	}
+}